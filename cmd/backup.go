@@ -0,0 +1,167 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/helm/helm-2to3/pkg/backup"
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+)
+
+var (
+	backupFile string
+)
+
+type BackupOptions struct {
+	DryRun           bool
+	FileName         string
+	ReleaseNames     []string
+	StorageType      string
+	TillerLabel      string
+	TillerNamespace  string
+	TillerOutCluster bool
+}
+
+func newBackupCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "backup [flags] [RELEASE...]",
+		Short: "back up Helm v2 release data to a local archive",
+		Long:  "Back up the Helm v2 release data of the specified releases, or of all releases if none is specified, to a local archive",
+		RunE:  runBackup,
+	}
+
+	flags := cmd.Flags()
+	settings.AddFlags(flags)
+
+	flags.StringVarP(&backupFile, "file", "f", "", "path of the backup archive to write. Defaults to 'helm-v2-backup-<timestamp>.tar.gz' in the current directory")
+
+	return cmd
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	if err := v2.ValidateStorageType(settings.ReleaseStorage); err != nil {
+		return err
+	}
+	backupOptions := BackupOptions{
+		DryRun:           settings.DryRun,
+		FileName:         backupFile,
+		ReleaseNames:     args,
+		StorageType:      settings.ReleaseStorage,
+		TillerLabel:      settings.Label,
+		TillerNamespace:  settings.TillerNamespace,
+		TillerOutCluster: settings.TillerOutCluster,
+	}
+	kubeConfig := common.KubeConfig{
+		Context: settings.KubeContext,
+		File:    settings.KubeConfigFile,
+	}
+
+	return Backup(backupOptions, kubeConfig)
+}
+
+// Backup writes the Helm v2 storage objects, ConfigMaps or Secrets, of the specified releases, or
+// of all releases if none is specified, to a gzipped tar archive. The archive has a manifest with
+// the checksum of each object, the Tiller namespace, the storage type and the capture time.
+// An existing archive is never overwritten.
+func Backup(backupOptions BackupOptions, kubeConfig common.KubeConfig) error {
+	backupLog := logger.With(logger.Fields{logger.FieldOperation: "backup", logger.FieldNamespace: backupOptions.TillerNamespace})
+	if backupOptions.DryRun {
+		backupLog.Infof("NOTE: This is in dry-run mode, the following actions will not be executed.")
+		backupLog.Infof("Run without --dry-run to take the actions described below:")
+		backupLog.Infof("")
+	}
+
+	retrieveOptions := v2.RetrieveOptions{
+		TillerNamespace:  backupOptions.TillerNamespace,
+		TillerLabel:      backupOptions.TillerLabel,
+		TillerOutCluster: backupOptions.TillerOutCluster,
+		StorageType:      backupOptions.StorageType,
+	}
+	var objects []runtime.Object
+	var storageType string
+	if len(backupOptions.ReleaseNames) <= 0 {
+		var err error
+		objects, storageType, err = v2.GetStorageObjectCopies(retrieveOptions, kubeConfig)
+		if err != nil {
+			return err
+		}
+	}
+	for _, releaseName := range backupOptions.ReleaseNames {
+		retrieveOptions.ReleaseName = releaseName
+		releaseObjects, releaseStorageType, err := v2.GetStorageObjectCopies(retrieveOptions, kubeConfig)
+		if err != nil {
+			return err
+		}
+		if len(releaseObjects) <= 0 {
+			return fmt.Errorf("[Helm 2] Release \"%s\" has no release versions to back up", releaseName)
+		}
+		objects = append(objects, releaseObjects...)
+		storageType = releaseStorageType
+	}
+	if len(objects) <= 0 {
+		return fmt.Errorf("[Helm 2] No release versions to back up in namespace \"%s\"", backupOptions.TillerNamespace)
+	}
+
+	for _, object := range objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return err
+		}
+		backupLog.Infof("[Helm 2] ReleaseVersion \"%s\" will be backed up.\n", accessor.GetName())
+	}
+
+	captureTime := time.Now().UTC()
+	fileName := backupOptions.FileName
+	if fileName == "" {
+		fileName = fmt.Sprintf("helm-v2-backup-%s.tar.gz", captureTime.Format("20060102T150405Z"))
+	}
+	backupLog.Infof("[Helm 2] %d release versions will be backed up to \"%s\".\n", len(objects), fileName)
+	if backupOptions.DryRun {
+		return nil
+	}
+
+	file, err := os.OpenFile(fileName, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("Failed to create backup archive \"%s\" due to the following error: %s", fileName, err)
+	}
+	manifest := backup.Manifest{
+		CaptureTime:     captureTime,
+		TillerNamespace: retrieveOptions.TillerNamespace,
+		StorageType:     storageType,
+	}
+	if err := backup.Write(file, manifest, objects); err != nil {
+		file.Close()
+		os.Remove(fileName)
+		return fmt.Errorf("Failed to write backup archive \"%s\" due to the following error: %s", fileName, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("Failed to write backup archive \"%s\" due to the following error: %s", fileName, err)
+	}
+	backupLog.Infof("[Helm 2] %d release versions backed up to \"%s\".\n", len(objects), fileName)
+
+	return nil
+}
@@ -20,12 +20,15 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
+	"github.com/helm/helm-2to3/pkg/plan"
 	utils "github.com/helm/helm-2to3/pkg/utils"
 	v2 "github.com/helm/helm-2to3/pkg/v2"
 )
@@ -39,8 +42,10 @@ var (
 )
 
 type CleanupOptions struct {
+	AllTillers       bool
 	ConfigCleanup    bool
 	DryRun           bool
+	Output           string
 	ReleaseName      string
 	ReleaseCleanup   bool
 	SkipConfirmation bool
@@ -64,7 +69,9 @@ func newCleanupCmd(out io.Writer) *cobra.Command {
 	flags := cmd.Flags()
 	settings.AddFlags(flags)
 
+	addAllTillersFlag(flags, "clean up all the Tillers found by the discover command, instead of the Tiller of --tiller-ns")
 	flags.BoolVar(&configCleanup, "config-cleanup", false, "if set, configuration cleanup performed")
+	addPlanFlags(flags)
 	flags.StringVar(&releaseName, "name", "", "the release name. When it is specified, the named release and its versions will be removed only. Should not be used with other cleanup operations")
 	flags.BoolVar(&releaseCleanup, "release-cleanup", false, "if set, release data cleanup performed")
 	flags.BoolVar(&skipConfirmation, "skip-confirmation", false, "if set, skips confirmation message before performing cleanup")
@@ -74,9 +81,20 @@ func newCleanupCmd(out io.Writer) *cobra.Command {
 }
 
 func runCleanup(cmd *cobra.Command, args []string) error {
+	if err := validatePlanOutput(planOutput, settings.DryRun); err != nil {
+		return err
+	}
+	if err := v2.ValidateStorageType(settings.ReleaseStorage); err != nil {
+		return err
+	}
+	if allTillers && (releaseName != "" || planOutput != "") {
+		return errors.New("name and output flags cannot be used with --all-tillers")
+	}
 	cleanupOptions := CleanupOptions{
+		AllTillers:       allTillers,
 		ConfigCleanup:    configCleanup,
 		DryRun:           settings.DryRun,
+		Output:           planOutput,
 		ReleaseCleanup:   releaseCleanup,
 		ReleaseName:      releaseName,
 		SkipConfirmation: skipConfirmation,
@@ -99,6 +117,7 @@ func runCleanup(cmd *cobra.Command, args []string) error {
 // the Tiller server deployed as per namespace and owner label. It is also delete the Helm gv2 home directory
 // which contains the Helm configuration. Helm v2 will be unusable after this operation.
 func Cleanup(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error {
+	cleanupLog := logger.With(logger.Fields{logger.FieldOperation: "cleanup", logger.FieldNamespace: cleanupOptions.TillerNamespace})
 	var message strings.Builder
 
 	if cleanupOptions.ReleaseName != "" {
@@ -115,9 +134,13 @@ func Cleanup(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error
 	}
 
 	if cleanupOptions.DryRun {
-		log.Println("NOTE: This is in dry-run mode, the following actions will not be executed.")
-		log.Println("Run without --dry-run to take the actions described below:")
-		log.Println()
+		cleanupLog.Infof("NOTE: This is in dry-run mode, the following actions will not be executed.")
+		cleanupLog.Infof("Run without --dry-run to take the actions described below:")
+		cleanupLog.Infof("")
+	}
+
+	if cleanupOptions.AllTillers {
+		return cleanupAllTillers(cleanupOptions, kubeConfig)
 	}
 
 	fmt.Fprint(&message, "WARNING: ")
@@ -142,12 +165,18 @@ func Cleanup(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error
 		fmt.Fprintln(&message, "Helm v2 may not be usable afterwards.")
 	}
 
-	fmt.Println(message.String())
+	// The plan is written to stdout, so the warning is written to stderr instead
+	cleanupPlan := newPlan("cleanup", cleanupOptions.Output)
+	if cleanupPlan != nil {
+		fmt.Fprintln(os.Stderr, message.String())
+	} else {
+		fmt.Println(message.String())
+	}
 
 	var doCleanup bool
 	var err error
-	if cleanupOptions.SkipConfirmation {
-		log.Println("Skipping confirmation before performing cleanup.")
+	if cleanupOptions.SkipConfirmation || cleanupPlan != nil {
+		cleanupLog.Infof("Skipping confirmation before performing cleanup.")
 		doCleanup = true
 		err = nil
 	} else {
@@ -157,17 +186,17 @@ func Cleanup(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error
 		return err
 	}
 	if !doCleanup {
-		log.Println("Cleanup will not proceed as the user didn't answer (Y|y) in order to continue.")
+		cleanupLog.Infof("Cleanup will not proceed as the user didn't answer (Y|y) in order to continue.")
 		return nil
 	}
 
-	log.Printf("\nHelm v2 data will be cleaned up.\n")
+	cleanupLog.Infof("\nHelm v2 data will be cleaned up.\n")
 
 	if cleanupOptions.ReleaseCleanup {
 		if cleanupOptions.ReleaseName == "" {
-			log.Println("[Helm 2] Releases will be deleted.")
+			cleanupLog.Infof("[Helm 2] Releases will be deleted.")
 		} else {
-			log.Printf("[Helm 2] Release '%s' will be deleted.\n", cleanupOptions.ReleaseName)
+			cleanupLog.Infof("[Helm 2] Release '%s' will be deleted.\n", cleanupOptions.ReleaseName)
 		}
 		retrieveOptions := v2.RetrieveOptions{
 			ReleaseName:      cleanupOptions.ReleaseName,
@@ -177,6 +206,26 @@ func Cleanup(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error
 			StorageType:      cleanupOptions.StorageType,
 		}
 		if cleanupOptions.ReleaseName == "" {
+			if cleanupPlan != nil {
+				v2Releases, err := v2.GetAllReleaseVersions(retrieveOptions, kubeConfig)
+				if err != nil {
+					return err
+				}
+				releaseNames := []string{}
+				for releaseName := range v2Releases {
+					releaseNames = append(releaseNames, releaseName)
+				}
+				sort.Strings(releaseNames)
+				for _, releaseName := range releaseNames {
+					versions := []int32{}
+					for _, v2Release := range v2Releases[releaseName] {
+						versions = append(versions, v2Release.Version)
+					}
+					if err := addStorageObjectsToPlan(cleanupPlan, retrieveOptions, releaseName, versions, kubeConfig); err != nil {
+						return err
+					}
+				}
+			}
 			err = v2.DeleteAllReleaseVersions(retrieveOptions, kubeConfig, cleanupOptions.DryRun)
 		} else {
 			// Get the releases versions as its the versions that are deleted
@@ -190,6 +239,9 @@ func Cleanup(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error
 				v2Release := v2Releases[i]
 				versions = append(versions, v2Release.Version)
 			}
+			if err := addStorageObjectsToPlan(cleanupPlan, retrieveOptions, cleanupOptions.ReleaseName, versions, kubeConfig); err != nil {
+				return err
+			}
 			deleteOptions := v2.DeleteOptions{
 				DryRun:   cleanupOptions.DryRun,
 				Versions: versions,
@@ -201,25 +253,25 @@ func Cleanup(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error
 		}
 		if !cleanupOptions.DryRun {
 			if cleanupOptions.ReleaseName == "" {
-				log.Println("[Helm 2] Releases deleted.")
+				cleanupLog.Infof("[Helm 2] Releases deleted.")
 			} else {
-				log.Printf("[Helm 2] Release '%s' deleted.\n", cleanupOptions.ReleaseName)
+				cleanupLog.Infof("[Helm 2] Release '%s' deleted.\n", cleanupOptions.ReleaseName)
 			}
 		}
 	}
 
 	if !cleanupOptions.TillerOutCluster && cleanupOptions.TillerCleanup {
-		log.Printf("[Helm 2] Tiller in \"%s\" namespace will be removed.\n", cleanupOptions.TillerNamespace)
-		err = v2.RemoveTiller(cleanupOptions.TillerNamespace, cleanupOptions.DryRun)
+		tiller, err := v2.DiscoverTiller(cleanupOptions.TillerNamespace, cleanupOptions.TillerLabel, kubeConfig)
 		if err != nil {
 			return err
 		}
-		if !cleanupOptions.DryRun {
-			log.Printf("[Helm 2] Tiller in \"%s\" namespace was removed.\n", cleanupOptions.TillerNamespace)
+		if err := removeTiller(cleanupLog, tiller, cleanupPlan, cleanupOptions.DryRun); err != nil {
+			return err
 		}
 	}
 
 	if cleanupOptions.ConfigCleanup {
+		cleanupPlan.AddHomeFolder(v2.HomeDir())
 		err = v2.RemoveHomeFolder(cleanupOptions.DryRun)
 		if err != nil {
 			return err
@@ -227,7 +279,102 @@ func Cleanup(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error
 	}
 
 	if !cleanupOptions.DryRun {
-		log.Println("Helm v2 data was cleaned up successfully.")
+		cleanupLog.Infof("Helm v2 data was cleaned up successfully.")
+	}
+	return cleanupPlan.Write(os.Stdout, cleanupOptions.Output)
+}
+
+// cleanupAllTillers cleans up the release data and the Tiller of each Tiller found in the cluster,
+// after a single confirmation. The Helm v2 configuration is removed once, at the end.
+func cleanupAllTillers(cleanupOptions CleanupOptions, kubeConfig common.KubeConfig) error {
+	tillers, err := discoverTillers(cleanupOptions.TillerLabel, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	namespaces := []string{}
+	for _, tiller := range tillers {
+		namespaces = append(namespaces, tiller.Namespace)
+	}
+	var message strings.Builder
+	fmt.Fprint(&message, "WARNING: ")
+	if cleanupOptions.ConfigCleanup {
+		fmt.Fprint(&message, "\"Helm v2 Configuration\" ")
+	}
+	if cleanupOptions.ReleaseCleanup {
+		fmt.Fprint(&message, "\"Release Data\" ")
+	}
+	if cleanupOptions.TillerCleanup {
+		fmt.Fprint(&message, "\"Tiller\" ")
+	}
+	fmt.Fprintf(&message, "will be removed for the Tillers in namespaces: %s. \n", strings.Join(namespaces, ", "))
+	if cleanupOptions.ReleaseCleanup {
+		fmt.Fprintln(&message, "This will clean up all releases managed by Helm v2. It will not be possible to restore them if you haven't made a backup of the releases.")
+	}
+	fmt.Fprintln(&message, "Helm v2 may not be usable afterwards.")
+	fmt.Println(message.String())
+
+	doCleanup := true
+	if cleanupOptions.SkipConfirmation {
+		logger.Infof("Skipping confirmation before performing cleanup.")
+	} else {
+		doCleanup, err = utils.AskConfirmation("Cleanup", "cleanup Helm v2 data")
+		if err != nil {
+			return err
+		}
+	}
+	if !doCleanup {
+		logger.Infof("Cleanup will not proceed as the user didn't answer (Y|y) in order to continue.")
+		return nil
+	}
+
+	logger.Infof("\nHelm v2 data will be cleaned up.\n")
+
+	for _, tiller := range tillers {
+		tillerLog := logger.With(logger.Fields{logger.FieldOperation: "cleanup", logger.FieldNamespace: tiller.Namespace})
+		if cleanupOptions.ReleaseCleanup && tiller.ReleaseVersions > 0 {
+			tillerLog.Infof("[Helm 2] Releases of Tiller in \"%s\" namespace will be deleted.\n", tiller.Namespace)
+			retrieveOptions := tillerRetrieveOptions(tiller, cleanupOptions.TillerLabel)
+			if err := v2.DeleteAllReleaseVersions(retrieveOptions, kubeConfig, cleanupOptions.DryRun); err != nil {
+				return err
+			}
+			if !cleanupOptions.DryRun {
+				tillerLog.Infof("[Helm 2] Releases of Tiller in \"%s\" namespace deleted.\n", tiller.Namespace)
+			}
+		}
+
+		if cleanupOptions.TillerCleanup && !tiller.Orphaned {
+			if err := removeTiller(tillerLog, tiller, nil, cleanupOptions.DryRun); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cleanupOptions.ConfigCleanup {
+		if err := v2.RemoveHomeFolder(cleanupOptions.DryRun); err != nil {
+			return err
+		}
+	}
+
+	if !cleanupOptions.DryRun {
+		logger.Infof("Helm v2 data was cleaned up successfully.")
+	}
+	return nil
+}
+
+// removeTiller removes the Deployment and Service of a discovered Tiller, and adds them to the plan
+// if there is one
+func removeTiller(tillerLog *logger.Logger, tiller v2.Tiller, cleanupPlan *plan.Plan, dryRun bool) error {
+	tillerLog.Infof("[Helm 2] Tiller in \"%s\" namespace will be removed.\n", tiller.Namespace)
+	cleanupPlan.AddTillerObject(plan.Object{Kind: "Deployment", Namespace: tiller.Namespace, Name: tiller.Deployment})
+	if tiller.Service != "" {
+		cleanupPlan.AddTillerObject(plan.Object{Kind: "Service", Namespace: tiller.Namespace, Name: tiller.Service})
+	}
+	if err := v2.RemoveTiller(tiller.Namespace, tiller.Deployment, tiller.Service, dryRun); err != nil {
+		return err
+	}
+	if !dryRun {
+		tillerLog.Infof("[Helm 2] Tiller in \"%s\" namespace was removed.\n", tiller.Namespace)
 	}
 	return nil
 }
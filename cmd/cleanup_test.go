@@ -0,0 +1,159 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v2rel "k8s.io/helm/pkg/proto/hapi/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/plan"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+)
+
+// setMemoryStorage sets an in-memory Helm v2 storage with the versions 1 and 2 of release 'app'
+// and the version 1 of release 'other'
+func setMemoryStorage(t *testing.T) *v2.MemoryStorage {
+	storage, err := v2.NewMemoryStorage(v2.StorageConfigMaps,
+		newStorageConfigMap(t, newV2Release("app", 1, v2rel.Status_SUPERSEDED)),
+		newStorageConfigMap(t, newV2Release("app", 2, v2rel.Status_DEPLOYED)),
+		newStorageConfigMap(t, newV2Release("other", 1, v2rel.Status_DEPLOYED)),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v2.SetStorage(storage)
+	t.Cleanup(func() {
+		v2.SetStorage(nil)
+	})
+	return storage
+}
+
+// storageObjectNames returns the names of the storage objects left in a storage
+func storageObjectNames(t *testing.T, storage v2.Storage) string {
+	objects, err := storage.List("OWNER=TILLER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, object := range objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, accessor.GetName())
+	}
+	return fmt.Sprint(names)
+}
+
+func TestCleanup(t *testing.T) {
+	tests := []struct {
+		name    string
+		options CleanupOptions
+		want    string
+	}{
+		{name: "release", options: CleanupOptions{ReleaseName: "app"}, want: "[other.v1]"},
+		{name: "all releases", options: CleanupOptions{ReleaseCleanup: true}, want: "[]"},
+		{name: "dry run", options: CleanupOptions{ReleaseCleanup: true, DryRun: true}, want: "[app.v1 app.v2 other.v1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := setMemoryStorage(t)
+			options := tt.options
+			options.SkipConfirmation = true
+			options.StorageType = "configmaps"
+			options.TillerLabel = "OWNER=TILLER"
+			options.TillerNamespace = "kube-system"
+			options.TillerOutCluster = true
+
+			if err := Cleanup(options, common.KubeConfig{}); err != nil {
+				t.Fatalf("Cleanup() failed: %s", err)
+			}
+			if names := storageObjectNames(t, storage); names != tt.want {
+				t.Errorf("Helm v2 storage objects left are %s, want %s", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestCleanupReleaseWithOtherOperations(t *testing.T) {
+	storage := setMemoryStorage(t)
+	options := CleanupOptions{ReleaseName: "app", TillerCleanup: true, SkipConfirmation: true, TillerOutCluster: true}
+
+	if err := Cleanup(options, common.KubeConfig{}); err == nil {
+		t.Fatal("Cleanup() of a release with Tiller cleanup succeeded, want error")
+	}
+	if names := storageObjectNames(t, storage); names != "[app.v1 app.v2 other.v1]" {
+		t.Errorf("Helm v2 storage objects left are %s, want all", names)
+	}
+}
+
+func TestCleanupTillerPlan(t *testing.T) {
+	cs := setFakeClientSet(t)
+	podLabels := map[string]string{"app": "tiller-custom"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiller-custom", Namespace: "kube-system"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "tiller", Image: "gcr.io/kubernetes-helm/tiller:v2.16.12"}},
+				},
+			},
+		},
+	}
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiller-custom-svc", Namespace: "kube-system"},
+		Spec:       corev1.ServiceSpec{Selector: podLabels},
+	}
+	if _, err := cs.AppsV1().Deployments("kube-system").Create(context.Background(), deployment, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.CoreV1().Services("kube-system").Create(context.Background(), service, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	options := CleanupOptions{TillerCleanup: true, DryRun: true, Output: "json", TillerNamespace: "kube-system"}
+
+	var err error
+	output := captureStdout(t, func() {
+		err = Cleanup(options, common.KubeConfig{})
+	})
+	if err != nil {
+		t.Fatalf("Cleanup() failed: %s", err)
+	}
+	var cleanupPlan plan.Plan
+	if err := json.Unmarshal(output, &cleanupPlan); err != nil {
+		t.Fatalf("plan %q is not JSON: %s", output, err)
+	}
+	// The discovered names are removed, not the names set by 'helm init'
+	want := []plan.Object{
+		{Kind: "Deployment", Namespace: "kube-system", Name: "tiller-custom"},
+		{Kind: "Service", Namespace: "kube-system", Name: "tiller-custom-svc"},
+	}
+	if !reflect.DeepEqual(cleanupPlan.TillerObjects, want) {
+		t.Errorf("plan removes Tiller objects %+v, want %+v", cleanupPlan.TillerObjects, want)
+	}
+}
@@ -17,44 +17,110 @@ limitations under the License.
 package cmd
 
 import (
+	"bytes"
 	"errors"
+	"fmt"
 	"io"
-	"log"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	corev1 "k8s.io/api/core/v1"
 	v2rel "k8s.io/helm/pkg/proto/hapi/release"
 
+	"github.com/helm/helm-2to3/pkg/checkpoint"
 	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
+	"github.com/helm/helm-2to3/pkg/plan"
 	v2 "github.com/helm/helm-2to3/pkg/v2"
 	v3 "github.com/helm/helm-2to3/pkg/v3"
 
+	"helm.sh/helm/v3/pkg/chartutil"
+	"helm.sh/helm/v3/pkg/release"
 	"helm.sh/helm/v3/pkg/storage/driver"
+	"sigs.k8s.io/yaml"
 )
 
 var (
+	adoptResources     bool
+	checkpointFile     string
+	convertAll         bool
 	deletev2Releases   bool
+	fromFile           string
+	keepFirstInstall   bool
 	maxReleaseVersions int
+	onlyDeployed       bool
+	since              string
+	skipStatuses       []string
+	noRollback         bool
+	resumeFile         string
+	selectChart        string
+	selectName         string
+	selectNamespace    string
+	selectStatuses     []string
+	toFile             string
+	// Shared with the verify command
+	apiMappingFile           string
+	renameFile               string
+	renames                  map[string]string
+	rewriteManifestNamespace bool
+	targetNamespace          string
+	// Shared with the preflight and verify commands
+	v3Driver        string
+	v3SQLConnection string
 	// new variable to ignore already migrated releases
 	ignoreAlreadyMigrated bool
 )
 
 type ConvertOptions struct {
-	DeleteRelease         bool
-	DryRun                bool
-	MaxReleaseVersions    int
-	ReleaseName           string
-	StorageType           string
-	TillerLabel           string
-	TillerNamespace       string
-	TillerOutCluster      bool
-	IgnoreAlreadyMigrated bool
+	AdoptResources           bool
+	APIMappings              []v3.APIMapping
+	AllReleases              bool
+	AllTillers               bool
+	CheckpointFile           string
+	DeleteRelease            bool
+	DryRun                   bool
+	Filter                   v2.FilterOptions
+	FromFile                 string
+	History                  v2.HistoryOptions
+	NoRollback               bool
+	Output                   string
+	ReleaseName              string
+	Renames                  map[string]string
+	ResumeFile               string
+	RewriteManifestNamespace bool
+	StorageType              string
+	TargetNamespace          string
+	TillerLabel              string
+	TillerNamespace          string
+	TillerOutCluster         bool
+	ToFile                   string
+	V3Driver                 string
+	V3SQLConnection          string
+	IgnoreAlreadyMigrated    bool
 }
 
 func newConvertCmd(out io.Writer) *cobra.Command {
 	cmd := &cobra.Command{
-		Use:   "convert [flags] RELEASE",
+		Use:   "convert [flags] RELEASE|--all|--from-file FILE",
 		Short: "migrate Helm v2 release in-place to Helm v3",
 		Args: func(cmd *cobra.Command, args []string) error {
+			if fromFile != "" {
+				if len(args) > 1 {
+					return errors.New("only one release can be defined when converting from a file")
+				}
+				return nil
+			}
+			if convertAll || !getConvertFilter().IsEmpty() {
+				if len(args) != 0 {
+					return errors.New("name of release cannot be defined when all releases or release selectors are used")
+				}
+				return nil
+			}
 			if len(args) != 1 {
 				return errors.New("name of release to be converted has to be defined")
 			}
@@ -67,29 +133,103 @@ func newConvertCmd(out io.Writer) *cobra.Command {
 	flags := cmd.Flags()
 	settings.AddFlags(flags)
 
+	flags.BoolVar(&adoptResources, "adopt-resources", false, "if set, the live resources of the release are labelled and annotated with the Helm v3 ownership metadata after conversion")
+	flags.BoolVar(&convertAll, "all", false, "convert all Helm v2 releases managed by Tiller")
+	addAllTillersFlag(flags, "convert the releases of all the Tillers found by the discover command, instead of the Tiller of --tiller-ns. Needs --all or release selectors")
+	flags.StringVar(&checkpointFile, "checkpoint", "", "path of a checkpoint file where each completed conversion step is recorded, so that the conversion can be resumed with --resume")
 	flags.BoolVar(&deletev2Releases, "delete-v2-releases", false, "v2 release versions are deleted after migration. By default, the v2 release versions are retained")
+	flags.StringVar(&fromFile, "from-file", "", "path of a file of Helm v2 storage ConfigMaps or Secrets to convert offline, without connecting to the cluster. All its releases are converted unless a release is defined. Needs --to-file")
 	flags.IntVar(&maxReleaseVersions, "release-versions-max", 10, "limit the maximum number of versions converted per release. Use 0 for no limit")
 	flags.BoolVar(&ignoreAlreadyMigrated, "ignore-already-migrated", false, "Ignore any already migrated release versions and continue migrating")
+	flags.BoolVar(&keepFirstInstall, "keep-first-install", false, "if set, the first version of a release is converted in addition to the latest versions limited by --release-versions-max")
+	flags.BoolVar(&onlyDeployed, "only-deployed", false, "if set, only the currently deployed version of a release is converted")
+	flags.StringVar(&since, "since", "", "convert only the release versions last deployed at or after this date, as '2006-01-02' or RFC3339")
+	flags.StringSliceVar(&skipStatuses, "skip-status", []string{}, "release versions with one of these statuses are not converted, e.g. 'FAILED,DELETED'")
+	flags.BoolVar(&noRollback, "no-rollback", false, "if set, Helm v3 release versions created before a conversion failure are kept instead of being rolled back. Used for debugging")
+	addMappingFlags(flags)
+	addPlanFlags(flags)
+	flags.StringVar(&resumeFile, "resume", "", "path of a checkpoint file of an interrupted conversion. Steps already completed are skipped and new steps are recorded in the file")
+	flags.StringVar(&selectChart, "chart", "", "convert only the releases of this chart name. Implies --all")
+	flags.StringVar(&selectName, "name", "", "convert only the releases whose name matches this glob pattern, e.g. 'payments-*'. Implies --all")
+	flags.StringVar(&selectNamespace, "release-namespace", "", "convert only the releases deployed in this namespace. Implies --all")
+	flags.BoolVar(&rewriteManifestNamespace, "rewrite-manifest-namespace", false, "if set with --target-namespace, the metadata.namespace of objects in the stored manifest and hooks is rewritten to the target namespace")
+	flags.StringSliceVar(&selectStatuses, "status", []string{}, "convert only the releases whose latest version has one of these statuses, e.g. 'DEPLOYED,FAILED'. Implies --all")
+	flags.StringVar(&targetNamespace, "target-namespace", "", "namespace of the Helm v3 release, when different from the namespace of the Helm v2 release. The release resources need to already be in this namespace")
+	flags.StringVar(&toFile, "to-file", "", "path of the file where the Helm v3 storage Secrets converted offline with --from-file are written")
+	addV3DriverFlags(flags)
 
 	return cmd
 
 }
 
 func runConvert(cmd *cobra.Command, args []string) error {
-	releaseName := args[0]
-	if settings.ReleaseStorage != "configmaps" && settings.ReleaseStorage != "secrets" {
-		return errors.New("release-storage flag needs to be 'configmaps' or 'secrets'")
+	var releaseName string
+	filter := getConvertFilter()
+	allReleases := convertAll || !filter.IsEmpty()
+	if !allReleases && len(args) > 0 {
+		releaseName = args[0]
+	}
+	if fromFile != "" || toFile != "" {
+		if fromFile == "" || toFile == "" {
+			return errors.New("from-file and to-file flags need to be set together")
+		}
+		if adoptResources || deletev2Releases || checkpointFile != "" || resumeFile != "" {
+			return errors.New("adopt-resources, checkpoint, delete-v2-releases and resume flags cannot be used when converting from a file")
+		}
+		allReleases = releaseName == ""
+	}
+	if allTillers {
+		if !allReleases || fromFile != "" {
+			return errors.New("all-tillers flag needs --all or release selectors")
+		}
+		if planOutput != "" || checkpointFile != "" || resumeFile != "" {
+			return errors.New("checkpoint, output and resume flags cannot be used with --all-tillers")
+		}
+	}
+	if err := v2.ValidateStorageType(settings.ReleaseStorage); err != nil {
+		return err
+	}
+	if err := validatePlanOutput(planOutput, settings.DryRun); err != nil {
+		return err
+	}
+	releaseRenames, err := getRenames()
+	if err != nil {
+		return err
+	}
+	history, err := getHistoryOptions()
+	if err != nil {
+		return err
+	}
+	apiMappings, err := getAPIMappings()
+	if err != nil {
+		return err
 	}
 	convertOptions := ConvertOptions{
-		DeleteRelease:         deletev2Releases,
-		DryRun:                settings.DryRun,
-		MaxReleaseVersions:    maxReleaseVersions,
-		ReleaseName:           releaseName,
-		StorageType:           settings.ReleaseStorage,
-		TillerLabel:           settings.Label,
-		TillerNamespace:       settings.TillerNamespace,
-		TillerOutCluster:      settings.TillerOutCluster,
-		IgnoreAlreadyMigrated: ignoreAlreadyMigrated,
+		AdoptResources:           adoptResources,
+		APIMappings:              apiMappings,
+		AllReleases:              allReleases,
+		AllTillers:               allTillers,
+		CheckpointFile:           checkpointFile,
+		DeleteRelease:            deletev2Releases,
+		DryRun:                   settings.DryRun,
+		Filter:                   filter,
+		FromFile:                 fromFile,
+		History:                  history,
+		NoRollback:               noRollback,
+		Output:                   planOutput,
+		ReleaseName:              releaseName,
+		Renames:                  releaseRenames,
+		ResumeFile:               resumeFile,
+		RewriteManifestNamespace: rewriteManifestNamespace,
+		StorageType:              settings.ReleaseStorage,
+		TargetNamespace:          targetNamespace,
+		TillerLabel:              settings.Label,
+		TillerNamespace:          settings.TillerNamespace,
+		TillerOutCluster:         settings.TillerOutCluster,
+		ToFile:                   toFile,
+		V3Driver:                 v3Driver,
+		V3SQLConnection:          v3SQLConnection,
+		IgnoreAlreadyMigrated:    ignoreAlreadyMigrated,
 	}
 	kubeConfig := common.KubeConfig{
 		Context: settings.KubeContext,
@@ -99,20 +239,135 @@ func runConvert(cmd *cobra.Command, args []string) error {
 	return Convert(convertOptions, kubeConfig)
 }
 
+// addMappingFlags adds the flags which change how release versions are mapped to Helm v3
+func addMappingFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&apiMappingFile, "api-mapping-file", "", "path of a YAML file which maps deprecated Kubernetes API versions to their replacement. The API versions in the stored manifests and hooks are mapped when set")
+	flags.StringToStringVar(&renames, "rename", map[string]string{}, "rename a release in Helm v3, as 'old=new'. Can be repeated")
+	flags.StringVar(&renameFile, "rename-file", "", "path of a YAML file which maps release names to their new names in Helm v3. Renames set with --rename take precedence")
+}
+
+// addV3DriverFlags adds the flags which select the Helm v3 storage driver
+func addV3DriverFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&v3Driver, "v3-driver", "", "Helm v3 storage driver, 'secret', 'configmap' or 'sql'. Defaults to $HELM_DRIVER if set, which takes precedence over the driver matching the Helm v2 storage type")
+	flags.StringVar(&v3SQLConnection, "v3-sql-connection", "", "connection string of the Helm v3 SQL storage driver. Defaults to $HELM_DRIVER_SQL_CONNECTION_STRING")
+}
+
+// setV3StorageDriver sets the Helm v3 storage driver. If the driver is not set, the HELM_DRIVER
+// environment variable is used if set, else the driver matching the Helm v2 storage type. The
+// source which chose the driver is logged, as HELM_DRIVER takes precedence over the storage type.
+func setV3StorageDriver(driverName, connection string, retrieveOptions v2.RetrieveOptions, kubeConfig common.KubeConfig) error {
+	source := "--v3-driver"
+	if driverName == "" {
+		driverName = os.Getenv("HELM_DRIVER")
+		source = "HELM_DRIVER"
+	}
+	if driverName == "" {
+		storageType, err := v2.GetStorageType(retrieveOptions, kubeConfig)
+		if err != nil {
+			return err
+		}
+		driverName = v3.DriverSecret
+		if storageType == "configmaps" {
+			driverName = v3.DriverConfigMap
+		}
+		source = fmt.Sprintf("Helm v2 storage type \"%s\"", storageType)
+	}
+	logger.Infof("[Helm 3] Storage driver \"%s\" is set from %s.\n", driverName, source)
+	if connection == "" {
+		connection = os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING")
+	}
+	return v3.SetStorageDriver(driverName, connection)
+}
+
+func getAPIMappings() ([]v3.APIMapping, error) {
+	if apiMappingFile == "" {
+		return nil, nil
+	}
+	return v3.LoadAPIMappings(apiMappingFile)
+}
+
+// getRenames returns the release renames from the rename file and flags
+func getRenames() (map[string]string, error) {
+	releaseRenames := map[string]string{}
+	if renameFile != "" {
+		data, err := ioutil.ReadFile(renameFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read rename file \"%s\" due to the following error: %s", renameFile, err)
+		}
+		if err := yaml.Unmarshal(data, &releaseRenames); err != nil {
+			return nil, fmt.Errorf("Failed to parse rename file \"%s\" due to the following error: %s", renameFile, err)
+		}
+	}
+	for oldName, newName := range renames {
+		releaseRenames[oldName] = newName
+	}
+	return releaseRenames, nil
+}
+
+func getHistoryOptions() (v2.HistoryOptions, error) {
+	history := v2.HistoryOptions{
+		KeepFirstInstall:   keepFirstInstall,
+		MaxReleaseVersions: maxReleaseVersions,
+		OnlyDeployed:       onlyDeployed,
+		SkipStatuses:       skipStatuses,
+	}
+	for _, status := range skipStatuses {
+		if strings.EqualFold(strings.TrimSpace(status), "DEPLOYED") {
+			return history, errors.New("skip-status flag cannot contain 'DEPLOYED' as the deployed version of a release is always converted")
+		}
+	}
+	if since != "" {
+		sinceTime, err := time.Parse("2006-01-02", since)
+		if err != nil {
+			sinceTime, err = time.Parse(time.RFC3339, since)
+		}
+		if err != nil {
+			return history, fmt.Errorf("since flag needs to be a date like '2006-01-02' or an RFC3339 time: %s", since)
+		}
+		history.Since = sinceTime
+	}
+	return history, nil
+}
+
+func getConvertFilter() v2.FilterOptions {
+	return v2.FilterOptions{
+		ChartName:   selectChart,
+		NamePattern: selectName,
+		Namespace:   selectNamespace,
+		Statuses:    selectStatuses,
+	}
+}
+
 // Convert converts Helm 2 release into Helm 3 release. It maps the Helm v2 release versions
 // of the release into Helm v3 equivalent and stores the release versions. The underlying Kubernetes resources
 // are untouched. Note: The namespaces of each release version need to exist in the Kubernetes  cluster.
 // The Helm 2 release is retained by default, unless the '--delete-v2-releases' flag is set.
+// When all releases are converted, a failure to convert a release does not stop the conversion
+// of the other releases. A summary of the conversion is reported at the end instead.
+// Completed steps are recorded in a checkpoint file when set, and the steps recorded in the
+// resume file are skipped.
 func Convert(convertOptions ConvertOptions, kubeConfig common.KubeConfig) error {
 	if convertOptions.DryRun {
-		log.Println("NOTE: This is in dry-run mode, the following actions will not be executed.")
-		log.Println("Run without --dry-run to take the actions described below:")
-		log.Println()
+		logger.Infof("NOTE: This is in dry-run mode, the following actions will not be executed.")
+		logger.Infof("Run without --dry-run to take the actions described below:")
+		logger.Infof("")
 	}
 
-	log.Printf("Release \"%s\" will be converted from Helm v2 to Helm v3.\n", convertOptions.ReleaseName)
+	if convertOptions.AllTillers {
+		return convertAllTillers(convertOptions, kubeConfig)
+	}
+	return convertTiller(convertOptions, kubeConfig)
+}
 
-	log.Printf("[Helm 3] Release \"%s\" will be created.\n", convertOptions.ReleaseName)
+// convertTiller converts the releases of the Tiller of the convert options
+func convertTiller(convertOptions ConvertOptions, kubeConfig common.KubeConfig) error {
+	convertPlan := newPlan("convert", convertOptions.Output)
+	if convertOptions.FromFile != "" {
+		if err := convertFromFile(convertOptions, convertPlan); err != nil {
+			return err
+		}
+		return convertPlan.Write(os.Stdout, convertOptions.Output)
+	}
 
 	retrieveOptions := v2.RetrieveOptions{
 		ReleaseName:      convertOptions.ReleaseName,
@@ -121,80 +376,621 @@ func Convert(convertOptions ConvertOptions, kubeConfig common.KubeConfig) error
 		TillerOutCluster: convertOptions.TillerOutCluster,
 		StorageType:      convertOptions.StorageType,
 	}
-	v2Releases, err := v2.GetReleaseVersions(retrieveOptions, kubeConfig)
+	if err := setV3StorageDriver(convertOptions.V3Driver, convertOptions.V3SQLConnection, retrieveOptions, kubeConfig); err != nil {
+		return err
+	}
+
+	journal, err := openCheckpoint(convertOptions)
 	if err != nil {
 		return err
 	}
+	defer journal.Close()
+
+	if convertOptions.AllReleases {
+		err = convertAllReleases(convertOptions, journal, convertPlan, kubeConfig)
+	} else {
+		var v2Releases []*v2rel.Release
+		v2Releases, err = v2.GetReleaseVersions(retrieveOptions, kubeConfig)
+		if err == nil {
+			err = convertRelease(convertOptions, v2Releases, journal, convertPlan, kubeConfig)
+		}
+		if err != nil {
+			convertPlan.AddFailedRelease(convertOptions.ReleaseName, err)
+		}
+	}
+
+	// The plan is written even if releases failed, so that a dry run reports the failed releases
+	writeErr := convertPlan.Write(os.Stdout, convertOptions.Output)
+	if err != nil {
+		return err
+	}
+	return writeErr
+}
+
+// convertAllTillers converts the releases of each Tiller found in the cluster, including the
+// storage left by removed Tillers. A Tiller failing to convert does not stop the others, but
+// releases of different Tillers which would be the same Helm v3 release stop the conversion
+// before any release is converted.
+func convertAllTillers(convertOptions ConvertOptions, kubeConfig common.KubeConfig) error {
+	tillers, err := discoverTillers(convertOptions.TillerLabel, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	tillerOptions := map[string]ConvertOptions{}
+	for _, tiller := range tillers {
+		retrieveOptions := tillerRetrieveOptions(tiller, convertOptions.TillerLabel)
+		options := convertOptions
+		options.AllTillers = false
+		options.StorageType = retrieveOptions.StorageType
+		options.TillerNamespace = retrieveOptions.TillerNamespace
+		options.TillerOutCluster = retrieveOptions.TillerOutCluster
+		tillerOptions[tiller.Namespace] = options
+	}
+	if err := checkTillerV3ReleaseCollisions(tillers, tillerOptions, kubeConfig); err != nil {
+		return err
+	}
+
+	failed := []string{}
+	for _, tiller := range tillers {
+		tillerLog := logger.With(logger.Fields{logger.FieldOperation: "convert", logger.FieldNamespace: tiller.Namespace})
+		if tiller.ReleaseVersions <= 0 {
+			tillerLog.Infof("[Helm 2] Tiller in namespace \"%s\" has no releases to convert.\n", tiller.Namespace)
+			continue
+		}
+		tillerLog.Infof("[Helm 2] Releases of Tiller in namespace \"%s\" will be converted.\n", tiller.Namespace)
+		if err := convertTiller(tillerOptions[tiller.Namespace], kubeConfig); err != nil {
+			tillerLog.Errorf("[Helm 2] Releases of Tiller in namespace \"%s\" failed to convert due to the following error: %s\n", tiller.Namespace, err)
+			failed = append(failed, tiller.Namespace)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("releases of %d of %d Tillers failed to convert, in namespaces: %s", len(failed), len(tillers), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// checkTillerV3ReleaseCollisions returns an error if releases of different Tillers would be converted
+// into the same Helm v3 release, by their namespace and Helm v3 name. Releases of the same Tiller
+// are checked when its releases are converted.
+func checkTillerV3ReleaseCollisions(tillers []v2.Tiller, tillerOptions map[string]ConvertOptions, kubeConfig common.KubeConfig) error {
+	v3Names := map[string]string{}
+	collisions := []string{}
+	for _, tiller := range tillers {
+		if tiller.ReleaseVersions <= 0 {
+			continue
+		}
+		options := tillerOptions[tiller.Namespace]
+		retrieveOptions := v2.RetrieveOptions{
+			TillerNamespace:  options.TillerNamespace,
+			TillerLabel:      options.TillerLabel,
+			TillerOutCluster: options.TillerOutCluster,
+			StorageType:      options.StorageType,
+		}
+		v2Releases, err := v2.GetAllReleaseVersions(retrieveOptions, kubeConfig)
+		if err != nil {
+			return err
+		}
+		if !options.Filter.IsEmpty() {
+			v2Releases, err = v2.FilterReleases(v2Releases, options.Filter)
+			if err != nil {
+				return err
+			}
+		}
+		releaseNames := []string{}
+		for releaseName := range v2Releases {
+			releaseNames = append(releaseNames, releaseName)
+		}
+		sort.Strings(releaseNames)
+
+		tillerV3Names := map[string]bool{}
+		for _, releaseName := range releaseNames {
+			v3Name := getV3ReleaseKey(releaseName, v2Releases[releaseName], options)
+			if v3Name == "" || tillerV3Names[v3Name] {
+				continue
+			}
+			tillerV3Names[v3Name] = true
+			tillerRelease := fmt.Sprintf("%s/%s", tiller.Namespace, releaseName)
+			if otherRelease, exists := v3Names[v3Name]; exists {
+				collisions = append(collisions, fmt.Sprintf("Helm v3 release \"%s\" would be created by both release \"%s\" and release \"%s\"", v3Name, otherRelease, tillerRelease))
+				continue
+			}
+			v3Names[v3Name] = tillerRelease
+		}
+	}
+	if len(collisions) > 0 {
+		return fmt.Errorf("releases of different Tillers collide, no release was converted: %s", strings.Join(collisions, "; "))
+	}
+	return nil
+}
+
+// convertFromFile converts the release versions of a file of Helm v2 storage objects into a file of
+// Helm v3 storage Secrets, which can be created in the cluster later on. It does not connect to the
+// cluster, so the release versions are all converted or none is written.
+func convertFromFile(convertOptions ConvertOptions, convertPlan *plan.Plan) error {
+	logger.Infof("[Helm 2] Release versions will be read from file \"%s\".\n", convertOptions.FromFile)
+	v2Releases, err := v2.GetAllReleaseVersionsFromFile(convertOptions.FromFile)
+	if err != nil {
+		return err
+	}
+	if !convertOptions.AllReleases {
+		versions, ok := v2Releases[convertOptions.ReleaseName]
+		if !ok {
+			return fmt.Errorf("%s has no deployed releases in file \"%s\"", convertOptions.ReleaseName, convertOptions.FromFile)
+		}
+		v2Releases = map[string][]*v2rel.Release{convertOptions.ReleaseName: versions}
+	}
+	if !convertOptions.Filter.IsEmpty() {
+		v2Releases, err = v2.FilterReleases(v2Releases, convertOptions.Filter)
+		if err != nil {
+			return err
+		}
+	}
+	if len(v2Releases) <= 0 {
+		return fmt.Errorf("[Helm 2] no releases to convert in file \"%s\"", convertOptions.FromFile)
+	}
+
+	releaseNames := []string{}
+	for releaseName := range v2Releases {
+		releaseNames = append(releaseNames, releaseName)
+	}
+	sort.Strings(releaseNames)
+
+	collisions := getV3ReleaseCollisions(releaseNames, v2Releases, convertOptions)
+	for _, releaseName := range releaseNames {
+		if err, collides := collisions[releaseName]; collides {
+			return err
+		}
+	}
+
+	secrets := []*corev1.Secret{}
+	for _, releaseName := range releaseNames {
+		releaseLog := logger.With(logger.Fields{logger.FieldOperation: "convert", logger.FieldRelease: releaseName})
+		releaseLog.Infof("Release \"%s\" will be converted from Helm v2 to Helm v3.\n", releaseName)
+		releaseOptions := convertOptions
+		releaseOptions.ReleaseName = releaseName
+		v3ReleaseName := getV3ReleaseName(releaseOptions)
+		if err := chartutil.ValidateReleaseName(v3ReleaseName); err != nil {
+			return fmt.Errorf("Release name \"%s\" is not valid in Helm v3: %s. Use --rename to convert it under a valid name", v3ReleaseName, err)
+		}
+		versions, err := v2.SelectReleaseVersions(v2Releases[releaseName], convertOptions.History)
+		if err != nil {
+			return err
+		}
+		for _, v2Release := range versions {
+			relVerName := v2.GetReleaseVersionName(v3ReleaseName, v2Release.Version)
+			versionLog := releaseLog.With(logger.Fields{logger.FieldVersion: v2Release.Version})
+			v3Release, substitutions, err := mapV3ReleaseVersion(v2Release, releaseOptions)
+			if err != nil {
+				return err
+			}
+			for _, sub := range substitutions {
+				versionLog.Infof("[Helm 3] ReleaseVersion \"%s\": %s \"%s\" in %s mapped from API version \"%s\" to \"%s\".\n", relVerName, sub.Kind, sub.Name, sub.Source, sub.From, sub.To)
+			}
+			secret, err := v3.ReleaseSecret(v3Release)
+			if err != nil {
+				return err
+			}
+			secrets = append(secrets, secret)
+			addReleaseVersionToPlan(convertPlan, releaseName, v3Release)
+			versionLog.With(logger.Fields{logger.FieldNamespace: v3Release.Namespace}).Infof("[Helm 3] ReleaseVersion \"%s\" will be written in namespace \"%s\".\n", relVerName, v3Release.Namespace)
+		}
+	}
+	if convertOptions.DryRun {
+		return nil
+	}
 
-	// Limit release versions to migrate.
-	// Limit is based on newest versions.
+	var b bytes.Buffer
+	for _, secret := range secrets {
+		data, err := yaml.Marshal(secret)
+		if err != nil {
+			return err
+		}
+		b.WriteString("---\n")
+		b.Write(data)
+	}
+	if err := ioutil.WriteFile(convertOptions.ToFile, b.Bytes(), 0600); err != nil {
+		return fmt.Errorf("Failed to write file \"%s\" due to the following error: %s", convertOptions.ToFile, err)
+	}
+	logger.Infof("[Helm 3] %d release versions of %d releases written to file \"%s\".\n", len(secrets), len(releaseNames), convertOptions.ToFile)
+
+	return nil
+}
+
+func openCheckpoint(convertOptions ConvertOptions) (*checkpoint.Journal, error) {
+	fileName := convertOptions.CheckpointFile
+	if convertOptions.ResumeFile != "" {
+		if fileName != "" && fileName != convertOptions.ResumeFile {
+			return nil, errors.New("checkpoint and resume flags need to refer to the same file when used together")
+		}
+		if _, err := os.Stat(convertOptions.ResumeFile); err != nil {
+			return nil, fmt.Errorf("Failed to read checkpoint file \"%s\" to resume from due to the following error: %s", convertOptions.ResumeFile, err)
+		}
+		fileName = convertOptions.ResumeFile
+		logger.Infof("Conversion will be resumed from checkpoint file \"%s\".\n", fileName)
+	}
+	if fileName == "" {
+		return nil, nil
+	}
+	return checkpoint.Open(fileName, convertOptions.DryRun)
+}
+
+func convertAllReleases(convertOptions ConvertOptions, journal *checkpoint.Journal, convertPlan *plan.Plan, kubeConfig common.KubeConfig) error {
+	if convertOptions.Filter.IsEmpty() {
+		logger.Infof("All releases will be converted from Helm v2 to Helm v3.")
+	} else {
+		logger.Infof("All releases matching the release selectors will be converted from Helm v2 to Helm v3.")
+	}
+
+	retrieveOptions := v2.RetrieveOptions{
+		TillerNamespace:  convertOptions.TillerNamespace,
+		TillerLabel:      convertOptions.TillerLabel,
+		TillerOutCluster: convertOptions.TillerOutCluster,
+		StorageType:      convertOptions.StorageType,
+	}
+	v2Releases, err := v2.GetAllReleaseVersions(retrieveOptions, kubeConfig)
+	if err != nil {
+		return err
+	}
+	if len(v2Releases) <= 0 {
+		logger.Infof("[Helm 2] no deployed releases for namespace: %s, owner: %s\n", convertOptions.TillerNamespace, convertOptions.TillerLabel)
+		return nil
+	}
+	if !convertOptions.Filter.IsEmpty() {
+		v2Releases, err = v2.FilterReleases(v2Releases, convertOptions.Filter)
+		if err != nil {
+			return err
+		}
+		if len(v2Releases) <= 0 {
+			logger.Infof("[Helm 2] no releases match the release selectors.")
+			return nil
+		}
+	}
+
+	releaseNames := []string{}
+	for releaseName := range v2Releases {
+		releaseNames = append(releaseNames, releaseName)
+	}
+	sort.Strings(releaseNames)
+
+	logger.Infof("[Helm 2] %d releases selected for conversion:\n", len(releaseNames))
+	for _, releaseName := range releaseNames {
+		versions := v2Releases[releaseName]
+		latest := versions[len(versions)-1]
+		logger.Infof("  Release \"%s\": namespace \"%s\", chart \"%s\", status %s, %d versions\n", releaseName, latest.Namespace, v2.GetChartName(latest), v2.GetStatus(latest), len(versions))
+	}
+
+	failures := getV3ReleaseCollisions(releaseNames, v2Releases, convertOptions)
+
+	for _, releaseName := range releaseNames {
+		if _, failed := failures[releaseName]; failed {
+			continue
+		}
+		logger.Infof("")
+		releaseOptions := convertOptions
+		releaseOptions.ReleaseName = releaseName
+		if err := convertRelease(releaseOptions, v2Releases[releaseName], journal, convertPlan, kubeConfig); err != nil {
+			logger.With(logger.Fields{logger.FieldOperation: "convert", logger.FieldRelease: releaseName}).Errorf("Release \"%s\" failed to convert from Helm v2 to Helm v3 with error: %s\n", releaseName, err)
+			failures[releaseName] = err
+		}
+	}
+
+	logger.Infof("")
+	logger.Infof("Conversion summary:")
+	for _, releaseName := range releaseNames {
+		if err, failed := failures[releaseName]; failed {
+			convertPlan.AddFailedRelease(releaseName, err)
+			logger.Errorf("  Release \"%s\": failed: %s\n", releaseName, err)
+		} else if convertOptions.DryRun {
+			logger.Infof("  Release \"%s\": to be converted\n", releaseName)
+		} else {
+			logger.Infof("  Release \"%s\": converted\n", releaseName)
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%d of %d releases failed to convert from Helm v2 to Helm v3", len(failures), len(releaseNames))
+	}
+
+	return nil
+}
+
+func convertRelease(convertOptions ConvertOptions, v2Releases []*v2rel.Release, journal *checkpoint.Journal, convertPlan *plan.Plan, kubeConfig common.KubeConfig) error {
+	namespace := v2Releases[len(v2Releases)-1].Namespace
+	if convertOptions.TargetNamespace != "" {
+		namespace = convertOptions.TargetNamespace
+	}
+	releaseLog := logger.With(logger.Fields{
+		logger.FieldOperation: "convert",
+		logger.FieldRelease:   convertOptions.ReleaseName,
+		logger.FieldNamespace: namespace,
+	})
+	releaseLog.Infof("Release \"%s\" will be converted from Helm v2 to Helm v3.\n", convertOptions.ReleaseName)
+
+	v3ReleaseName := getV3ReleaseName(convertOptions)
+	if v3ReleaseName != convertOptions.ReleaseName {
+		releaseLog.Infof("Release \"%s\" will be renamed to \"%s\" in Helm v3.\n", convertOptions.ReleaseName, v3ReleaseName)
+	}
+	if err := chartutil.ValidateReleaseName(v3ReleaseName); err != nil {
+		return fmt.Errorf("Release name \"%s\" is not valid in Helm v3: %s. Use --rename to convert it under a valid name", v3ReleaseName, err)
+	}
+
+	releaseLog.Infof("[Helm 3] Release \"%s\" will be created.\n", v3ReleaseName)
+
+	retrieveOptions := v2.RetrieveOptions{
+		ReleaseName:      convertOptions.ReleaseName,
+		TillerNamespace:  convertOptions.TillerNamespace,
+		TillerLabel:      convertOptions.TillerLabel,
+		TillerOutCluster: convertOptions.TillerOutCluster,
+		StorageType:      convertOptions.StorageType,
+	}
+
+	if convertOptions.TargetNamespace != "" {
+		// The resources of the deployed release version are the ones live in the cluster, which
+		// differ from the ones of the latest version after a failed upgrade
+		deployed, _, err := mapV3ReleaseVersion(getDeployedVersion(v2Releases), convertOptions)
+		if err != nil {
+			return err
+		}
+		releaseLog.Infof("[Helm 3] Release \"%s\" will be created in namespace \"%s\".\n", convertOptions.ReleaseName, convertOptions.TargetNamespace)
+		if err := v3.CheckResourcesInNamespace(deployed.Manifest, convertOptions.TargetNamespace, kubeConfig); err != nil {
+			return err
+		}
+	}
+
+	// Select release versions to migrate.
 	v2RelVerLen := len(v2Releases)
-	startIndex := 0
-	if convertOptions.MaxReleaseVersions > 0 && convertOptions.MaxReleaseVersions < v2RelVerLen {
-		log.Println()
-		log.Printf("NOTE: The max release versions \"%d\" is less than the actual release versions \"%d\".", convertOptions.MaxReleaseVersions, v2RelVerLen)
-		log.Printf("This means only \"%d\" of the latest release versions will be converted.", convertOptions.MaxReleaseVersions)
+	v2Releases, err := v2.SelectReleaseVersions(v2Releases, convertOptions.History)
+	if err != nil {
+		return err
+	}
+	if len(v2Releases) < v2RelVerLen {
+		releaseLog.Infof("")
+		if convertOptions.History.MaxReleaseVersions > 0 && convertOptions.History.MaxReleaseVersions < v2RelVerLen {
+			releaseLog.Warnf("NOTE: The max release versions \"%d\" is less than the actual release versions \"%d\".", convertOptions.History.MaxReleaseVersions, v2RelVerLen)
+		}
+		releaseLog.Warnf("This means only \"%d\" of the \"%d\" release versions will be converted, as per the release versions max and history flags.", len(v2Releases), v2RelVerLen)
 		if convertOptions.DeleteRelease {
-			log.Println("This also means some versions will remain in Helm v2 storage that will no longer be visible to Helm v2 commands like 'helm list'. Plugin 'cleanup' command will remove them from storage.")
+			releaseLog.Warnf("This also means some versions will remain in Helm v2 storage that will no longer be visible to Helm v2 commands like 'helm list'. Plugin 'cleanup' command will remove them from storage.")
 		}
-		log.Println()
-		startIndex = v2RelVerLen - convertOptions.MaxReleaseVersions
+		releaseLog.Infof("")
 	}
 
 	versions := []int32{}
-	for i := startIndex; i < v2RelVerLen; i++ {
-		v2Release := v2Releases[i]
-		relVerName := v2.GetReleaseVersionName(convertOptions.ReleaseName, v2Release.Version)
-		log.Printf("[Helm 3] ReleaseVersion \"%s\" will be created.\n", relVerName)
+	// Release versions created in this run, which are rolled back on failure
+	createdReleases := []*release.Release{}
+	rollback := func(err error) error {
+		if convertOptions.NoRollback {
+			releaseLog.Warnf("[Helm 3] ReleaseVersions created for release \"%s\" are not rolled back as rollback is disabled.\n", convertOptions.ReleaseName)
+			return err
+		}
+		if rollbackErr := rollbackV3ReleaseVersions(convertOptions.ReleaseName, createdReleases, journal, kubeConfig); rollbackErr != nil {
+			return fmt.Errorf("%s. Rollback of release \"%s\" also failed with error: %s", err, convertOptions.ReleaseName, rollbackErr)
+		}
+		return err
+	}
+	for _, v2Release := range v2Releases {
+		relVerName := v2.GetReleaseVersionName(v3ReleaseName, v2Release.Version)
+		versionLog := releaseLog.With(logger.Fields{logger.FieldVersion: v2Release.Version})
+		if journal.IsDone(convertOptions.ReleaseName, v2Release.Version, checkpoint.StepCreateV3) {
+			versionLog.Infof("[Helm 3] ReleaseVersion \"%s\" already created as per checkpoint.\n", relVerName)
+			versions = append(versions, v2Release.Version)
+			continue
+		}
+		versionLog.Infof("[Helm 3] ReleaseVersion \"%s\" will be created.\n", relVerName)
+		v3Release, substitutions, err := mapV3ReleaseVersion(v2Release, convertOptions)
+		if err != nil {
+			return rollback(err)
+		}
+		for _, sub := range substitutions {
+			versionLog.Infof("[Helm 3] ReleaseVersion \"%s\": %s \"%s\" in %s mapped from API version \"%s\" to \"%s\".\n", relVerName, sub.Kind, sub.Name, sub.Source, sub.From, sub.To)
+		}
+		addReleaseVersionToPlan(convertPlan, convertOptions.ReleaseName, v3Release)
 		if !convertOptions.DryRun {
-			if err := createV3ReleaseVersion(v2Release, kubeConfig); err != nil {
+			if err := v3.StoreRelease(v3Release, kubeConfig); err != nil {
 
 				if convertOptions.IgnoreAlreadyMigrated {
 					if driver.ErrReleaseExists.Error() == err.Error() {
-						log.Printf("[Helm 3] ReleaseVersion \"%s\" already exists.\n", relVerName)
+						versionLog.Warnf("[Helm 3] ReleaseVersion \"%s\" already exists.\n", relVerName)
 						continue
 					}
 				}
 
-				return err
+				return rollback(err)
+			}
+			createdReleases = append(createdReleases, v3Release)
+			versionLog.Infof("[Helm 3] ReleaseVersion \"%s\" created.\n", relVerName)
+			if err := journal.Record(convertOptions.ReleaseName, v2Release.Version, checkpoint.StepCreateV3); err != nil {
+				return rollback(err)
 			}
-			log.Printf("[Helm 3] ReleaseVersion \"%s\" created.\n", relVerName)
 		}
 		versions = append(versions, v2Release.Version)
 	}
 	if !convertOptions.DryRun {
-		log.Printf("[Helm 3] Release \"%s\" created.\n", convertOptions.ReleaseName)
+		releaseLog.Infof("[Helm 3] Release \"%s\" created.\n", v3ReleaseName)
 	}
 
-	if convertOptions.DeleteRelease {
-		log.Printf("[Helm 2] Release \"%s\" will be deleted.\n", convertOptions.ReleaseName)
-		deleteOptions := v2.DeleteOptions{
-			DryRun:   convertOptions.DryRun,
-			Versions: versions,
+	if convertOptions.AdoptResources && len(v2Releases) > 0 {
+		if err := adoptV3ReleaseResources(getDeployedVersion(v2Releases), convertOptions, kubeConfig); err != nil {
+			return rollback(err)
 		}
-		if err := v2.DeleteReleaseVersions(retrieveOptions, deleteOptions, kubeConfig); err != nil {
-			return err
+	}
+
+	if convertOptions.DeleteRelease {
+		releaseLog.Infof("[Helm 2] Release \"%s\" will be deleted.\n", convertOptions.ReleaseName)
+		for _, version := range versions {
+			if journal.IsDone(convertOptions.ReleaseName, version, checkpoint.StepDeleteV2) {
+				releaseLog.With(logger.Fields{logger.FieldVersion: version}).Infof("[Helm 2] ReleaseVersion \"%s\" already deleted as per checkpoint.\n", v2.GetReleaseVersionName(convertOptions.ReleaseName, version))
+				continue
+			}
+			if err := addStorageObjectsToPlan(convertPlan, retrieveOptions, convertOptions.ReleaseName, []int32{version}, kubeConfig); err != nil {
+				return err
+			}
+			deleteOptions := v2.DeleteOptions{
+				DryRun:   convertOptions.DryRun,
+				Versions: []int32{version},
+			}
+			if err := v2.DeleteReleaseVersions(retrieveOptions, deleteOptions, kubeConfig); err != nil {
+				return err
+			}
+			if !convertOptions.DryRun {
+				if err := journal.Record(convertOptions.ReleaseName, version, checkpoint.StepDeleteV2); err != nil {
+					return err
+				}
+			}
 		}
 		if !convertOptions.DryRun {
-			log.Printf("[Helm 2] Release \"%s\" deleted.\n", convertOptions.ReleaseName)
+			releaseLog.Infof("[Helm 2] Release \"%s\" deleted.\n", convertOptions.ReleaseName)
 
-			log.Printf("Release \"%s\" was converted successfully from Helm v2 to Helm v3.\n", convertOptions.ReleaseName)
+			releaseLog.Infof("Release \"%s\" was converted successfully from Helm v2 to Helm v3.\n", convertOptions.ReleaseName)
 		}
 	} else {
 		if !convertOptions.DryRun {
-			log.Printf("Release \"%s\" was converted successfully from Helm v2 to Helm v3.\n", convertOptions.ReleaseName)
-			log.Println("Note: The v2 release information still remains and should be removed to avoid conflicts with the migrated v3 release.")
-			log.Println("v2 release information should only be removed using `helm 2to3` cleanup and when all releases have been migrated over.")
+			releaseLog.Infof("Release \"%s\" was converted successfully from Helm v2 to Helm v3.\n", convertOptions.ReleaseName)
+			releaseLog.Infof("Note: The v2 release information still remains and should be removed to avoid conflicts with the migrated v3 release.")
+			releaseLog.Infof("v2 release information should only be removed using `helm 2to3` cleanup and when all releases have been migrated over.")
 		}
 	}
 
 	return nil
 }
 
-func createV3ReleaseVersion(v2Release *v2rel.Release, kubeConfig common.KubeConfig) error {
+// mapV3ReleaseVersion maps a Helm v2 release version to Helm v3 and applies the conversion options to it
+// It also returns the deprecated API versions substituted in the manifests.
+func mapV3ReleaseVersion(v2Release *v2rel.Release, convertOptions ConvertOptions) (*release.Release, []v3.APISubstitution, error) {
 	v3Release, err := v3.CreateRelease(v2Release)
+	if err != nil {
+		return nil, nil, err
+	}
+	if convertOptions.TargetNamespace != "" {
+		if err := v3.SetNamespace(v3Release, convertOptions.TargetNamespace, convertOptions.RewriteManifestNamespace); err != nil {
+			return nil, nil, err
+		}
+	}
+	v3Release.Name = getV3ReleaseName(convertOptions)
+	substitutions, err := v3.MapDeprecatedAPIs(v3Release, convertOptions.APIMappings)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v3Release, substitutions, nil
+}
+
+// getV3ReleaseCollisions returns an error for each release which would be converted into the same
+// Helm v3 release as another release. Releases become namespace scoped in Helm v3, and renames and
+// the target namespace can also make names collide, so the namespace and name of the Helm v3
+// release are compared.
+func getV3ReleaseCollisions(releaseNames []string, v2Releases map[string][]*v2rel.Release, convertOptions ConvertOptions) map[string]error {
+	collisions := map[string]error{}
+	v3Names := map[string]string{}
+	for _, releaseName := range releaseNames {
+		versions := v2Releases[releaseName]
+		if len(versions) == 0 {
+			continue
+		}
+		v3Name := getV3ReleaseKey(releaseName, versions, convertOptions)
+		if otherName, exists := v3Names[v3Name]; exists {
+			err := fmt.Errorf("Helm v3 release \"%s\" would be created by both release \"%s\" and release \"%s\"", v3Name, otherName, releaseName)
+			collisions[otherName] = err
+			collisions[releaseName] = err
+			continue
+		}
+		v3Names[v3Name] = releaseName
+	}
+	return collisions
+}
+
+// getV3ReleaseKey returns the namespace and name of the Helm v3 release which the release versions of
+// a Helm v2 release are converted into, as "namespace/name", or an empty string if it has no versions
+func getV3ReleaseKey(releaseName string, versions []*v2rel.Release, convertOptions ConvertOptions) string {
+	if len(versions) == 0 {
+		return ""
+	}
+	releaseOptions := convertOptions
+	releaseOptions.ReleaseName = releaseName
+	namespace := versions[len(versions)-1].Namespace
+	if convertOptions.TargetNamespace != "" {
+		namespace = convertOptions.TargetNamespace
+	}
+	return fmt.Sprintf("%s/%s", namespace, getV3ReleaseName(releaseOptions))
+}
+
+// getDeployedVersion returns the deployed version of a release, whose resources are the ones live
+// in the cluster, else the latest version. The versions need to be sorted by version.
+func getDeployedVersion(versions []*v2rel.Release) *v2rel.Release {
+	for i := len(versions) - 1; i >= 0; i-- {
+		if v2.GetStatus(versions[i]) == v2rel.Status_DEPLOYED.String() {
+			return versions[i]
+		}
+	}
+	return versions[len(versions)-1]
+}
+
+// getV3ReleaseName returns the name of the release in Helm v3, which differs from
+// the name of the Helm v2 release when it is renamed
+func getV3ReleaseName(convertOptions ConvertOptions) string {
+	if newName, ok := convertOptions.Renames[convertOptions.ReleaseName]; ok && newName != "" {
+		return newName
+	}
+	return convertOptions.ReleaseName
+}
+
+// adoptV3ReleaseResources sets the Helm v3 ownership metadata on the live resources of a release version
+func adoptV3ReleaseResources(v2Release *v2rel.Release, convertOptions ConvertOptions, kubeConfig common.KubeConfig) error {
+	v3Release, _, err := mapV3ReleaseVersion(v2Release, convertOptions)
 	if err != nil {
 		return err
 	}
-	return v3.StoreRelease(v3Release, kubeConfig)
+	adoptLog := logger.With(logger.Fields{
+		logger.FieldOperation: "adopt",
+		logger.FieldRelease:   v3Release.Name,
+		logger.FieldNamespace: v3Release.Namespace,
+	})
+	adoptLog.Infof("[Helm 3] Resources of release \"%s\" will be adopted.\n", v3Release.Name)
+	results, err := v3.AdoptResources(v3Release, convertOptions.DryRun, kubeConfig)
+	if err != nil {
+		return fmt.Errorf("[Helm 3] Resources of release \"%s\" failed to be adopted with error: %s", v3Release.Name, err)
+	}
+	for _, result := range results {
+		resource := fmt.Sprintf("%s \"%s\"", result.Kind, result.Name)
+		if result.Namespace != "" {
+			resource = fmt.Sprintf("%s in namespace \"%s\"", resource, result.Namespace)
+		}
+		switch result.Outcome {
+		case v3.AdoptMissing:
+			adoptLog.Warnf("[Helm 3] WARNING: %s is missing and was not adopted.\n", resource)
+		case v3.AdoptOwned:
+			adoptLog.Warnf("[Helm 3] WARNING: %s is owned by release \"%s\" and was not adopted.\n", resource, result.Owner)
+		default:
+			if convertOptions.DryRun {
+				adoptLog.Infof("[Helm 3] %s will be adopted.\n", resource)
+			} else {
+				adoptLog.Infof("[Helm 3] %s adopted.\n", resource)
+			}
+		}
+	}
+	return nil
+}
+
+// rollbackV3ReleaseVersions deletes the Helm v3 release versions created in the current run,
+// newest first, so that the release is back to its state before the run
+func rollbackV3ReleaseVersions(releaseName string, v3Releases []*release.Release, journal *checkpoint.Journal, kubeConfig common.KubeConfig) error {
+	for i := len(v3Releases) - 1; i >= 0; i-- {
+		v3Release := v3Releases[i]
+		relVerName := v2.GetReleaseVersionName(v3Release.Name, int32(v3Release.Version))
+		versionLog := logger.With(logger.Fields{
+			logger.FieldOperation: "rollback",
+			logger.FieldRelease:   releaseName,
+			logger.FieldNamespace: v3Release.Namespace,
+			logger.FieldVersion:   v3Release.Version,
+		})
+		versionLog.Infof("[Helm 3] ReleaseVersion \"%s\" will be rolled back.\n", relVerName)
+		if err := v3.DeleteRelease(v3Release, kubeConfig); err != nil {
+			return fmt.Errorf("[Helm 3] ReleaseVersion \"%s\" failed to roll back with error: %s", relVerName, err)
+		}
+		versionLog.Infof("[Helm 3] ReleaseVersion \"%s\" rolled back.\n", relVerName)
+		if err := journal.Record(releaseName, int32(v3Release.Version), checkpoint.StepRollbackV3); err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,377 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	v2rel "k8s.io/helm/pkg/proto/hapi/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/plan"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+	v3 "github.com/helm/helm-2to3/pkg/v3"
+)
+
+// newV2Release returns a Helm v2 release version of the chart 'app' in the 'default' namespace
+func newV2Release(name string, version int32, status v2rel.Status_Code) *v2rel.Release {
+	return &v2rel.Release{
+		Name:      name,
+		Version:   version,
+		Namespace: "default",
+		Chart: &chart.Chart{
+			Metadata: &chart.Metadata{Name: "app", Version: "1.0.0", ApiVersion: "v1"},
+		},
+		Config: &chart.Config{Raw: "replicas: 1\n"},
+		Info: &v2rel.Info{
+			Status:        &v2rel.Status{Code: status},
+			FirstDeployed: &timestamp.Timestamp{Seconds: 1600000000},
+			LastDeployed:  &timestamp.Timestamp{Seconds: 1600000000 + int64(version)},
+			Description:   "Install complete",
+		},
+		Manifest: fmt.Sprintf("---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s-config\n", name),
+	}
+}
+
+// newStorageConfigMap returns the ConfigMap storing a Helm v2 release version in the 'kube-system'
+// namespace, encoded as Tiller does
+func newStorageConfigMap(t *testing.T, release *v2rel.Release) *corev1.ConfigMap {
+	data, err := proto.Marshal(release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	if _, err := gzipWriter.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      v2.GetReleaseVersionName(release.Name, release.Version),
+			Namespace: "kube-system",
+			Labels: map[string]string{
+				"NAME":    release.Name,
+				"OWNER":   "TILLER",
+				"STATUS":  release.Info.Status.Code.String(),
+				"VERSION": fmt.Sprint(release.Version),
+			},
+		},
+		Data: map[string]string{"release": base64.StdEncoding.EncodeToString(compressed.Bytes())},
+	}
+}
+
+// setFakeClientSet sets a fake client set with the storage objects for Helm v2 and Helm v3,
+// which stores the converted releases in Secrets
+func setFakeClientSet(t *testing.T, objects ...*corev1.ConfigMap) kubernetes.Interface {
+	cs := fake.NewSimpleClientset()
+	for _, object := range objects {
+		if _, err := cs.CoreV1().ConfigMaps(object.Namespace).Create(context.Background(), object, metav1.CreateOptions{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v2.SetClientSet(cs)
+	v3.SetClientSet(cs)
+	t.Cleanup(func() {
+		v2.SetClientSet(nil)
+		v3.SetClientSet(nil)
+		v3.SetStorageDriver(v3.DriverSecret, "")
+	})
+	return cs
+}
+
+func newConvertOptions(releaseName string) ConvertOptions {
+	return ConvertOptions{
+		ReleaseName:      releaseName,
+		StorageType:      "configmaps",
+		TillerLabel:      "OWNER=TILLER",
+		TillerNamespace:  "kube-system",
+		TillerOutCluster: true,
+		V3Driver:         v3.DriverSecret,
+	}
+}
+
+// captureStdout returns what a function writes to stdout, as plans are written to stdout
+func captureStdout(t *testing.T, f func()) []byte {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = writer
+	defer func() {
+		os.Stdout = stdout
+	}()
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- data
+	}()
+	f()
+	writer.Close()
+	return <-output
+}
+
+func TestConvert(t *testing.T) {
+	cs := setFakeClientSet(t,
+		newStorageConfigMap(t, newV2Release("app", 1, v2rel.Status_SUPERSEDED)),
+		newStorageConfigMap(t, newV2Release("app", 2, v2rel.Status_DEPLOYED)),
+		newStorageConfigMap(t, newV2Release("other", 1, v2rel.Status_DEPLOYED)),
+	)
+	convertOptions := newConvertOptions("app")
+	convertOptions.DeleteRelease = true
+
+	if err := Convert(convertOptions, common.KubeConfig{}); err != nil {
+		t.Fatalf("Convert() failed: %s", err)
+	}
+
+	secrets, err := cs.CoreV1().Secrets("default").List(context.Background(), metav1.ListOptions{LabelSelector: "owner=helm,name=app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[string]string{}
+	for _, secret := range secrets.Items {
+		statuses[secret.Name] = secret.Labels["status"]
+	}
+	want := map[string]string{"sh.helm.release.v1.app.v1": "superseded", "sh.helm.release.v1.app.v2": "deployed"}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("Helm v3 release versions are %v, want %v", statuses, want)
+	}
+
+	configMaps, err := cs.CoreV1().ConfigMaps("kube-system").List(context.Background(), metav1.ListOptions{LabelSelector: "OWNER=TILLER"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(configMaps.Items) != 1 || configMaps.Items[0].Name != "other.v1" {
+		t.Errorf("Helm v2 storage has %d objects left, want only \"other.v1\"", len(configMaps.Items))
+	}
+}
+
+func TestConvertDryRun(t *testing.T) {
+	cs := setFakeClientSet(t, newStorageConfigMap(t, newV2Release("app", 1, v2rel.Status_DEPLOYED)))
+	convertOptions := newConvertOptions("app")
+	convertOptions.DeleteRelease = true
+	convertOptions.DryRun = true
+
+	if err := Convert(convertOptions, common.KubeConfig{}); err != nil {
+		t.Fatalf("Convert() failed: %s", err)
+	}
+
+	secrets, err := cs.CoreV1().Secrets("default").List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(secrets.Items) != 0 {
+		t.Errorf("dry run created %d Helm v3 release versions", len(secrets.Items))
+	}
+	if _, err := cs.CoreV1().ConfigMaps("kube-system").Get(context.Background(), "app.v1", metav1.GetOptions{}); err != nil {
+		t.Errorf("dry run deleted the Helm v2 release version: %s", err)
+	}
+}
+
+func TestConvertWithoutSelectedVersions(t *testing.T) {
+	cs := setFakeClientSet(t, newStorageConfigMap(t, newV2Release("app", 1, v2rel.Status_FAILED)))
+	convertOptions := newConvertOptions("app")
+	convertOptions.DeleteRelease = true
+	convertOptions.History.OnlyDeployed = true
+
+	if err := Convert(convertOptions, common.KubeConfig{}); err == nil {
+		t.Fatal("Convert() succeeded, want error as no version is selected")
+	}
+	if _, err := cs.CoreV1().ConfigMaps("kube-system").Get(context.Background(), "app.v1", metav1.GetOptions{}); err != nil {
+		t.Errorf("the Helm v2 release version was deleted: %s", err)
+	}
+}
+
+func TestConvertAllReleasesPlanWithFailure(t *testing.T) {
+	broken := newV2Release("broken", 1, v2rel.Status_DEPLOYED)
+	broken.Chart = nil
+	setFakeClientSet(t,
+		newStorageConfigMap(t, newV2Release("app", 1, v2rel.Status_DEPLOYED)),
+		newStorageConfigMap(t, broken),
+	)
+	convertOptions := newConvertOptions("")
+	convertOptions.AllReleases = true
+	convertOptions.DryRun = true
+	convertOptions.Output = plan.FormatJSON
+
+	var err error
+	output := captureStdout(t, func() {
+		err = Convert(convertOptions, common.KubeConfig{})
+	})
+	if err == nil {
+		t.Fatal("Convert() succeeded, want error for the broken release")
+	}
+
+	convertPlan := new(plan.Plan)
+	if err := json.Unmarshal(output, convertPlan); err != nil {
+		t.Fatalf("plan is not written: %s: %q", err, output)
+	}
+	if len(convertPlan.ReleaseVersions) != 1 || convertPlan.ReleaseVersions[0].V2Release != "app" {
+		t.Errorf("plan creates %v, want the version of release \"app\"", convertPlan.ReleaseVersions)
+	}
+	if len(convertPlan.FailedReleases) != 1 || convertPlan.FailedReleases[0].V2Release != "broken" {
+		t.Errorf("plan has failed releases %v, want release \"broken\"", convertPlan.FailedReleases)
+	}
+}
+
+func TestConvertAllTillers(t *testing.T) {
+	tests := []struct {
+		name           string
+		otherRelease   string
+		wantErr        bool
+		wantV3Releases int
+	}{
+		{name: "different releases", otherRelease: "other", wantV3Releases: 2},
+		{name: "same release", otherRelease: "app", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			otherConfigMap := newStorageConfigMap(t, newV2Release(tt.otherRelease, 1, v2rel.Status_DEPLOYED))
+			otherConfigMap.Namespace = "tiller-b"
+			cs := setFakeClientSet(t, newStorageConfigMap(t, newV2Release("app", 1, v2rel.Status_DEPLOYED)), otherConfigMap)
+			convertOptions := newConvertOptions("")
+			convertOptions.AllReleases = true
+			convertOptions.AllTillers = true
+
+			err := Convert(convertOptions, common.KubeConfig{})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Convert() error = %v, want error %t", err, tt.wantErr)
+			}
+
+			secrets, err := cs.CoreV1().Secrets("default").List(context.Background(), metav1.ListOptions{LabelSelector: "owner=helm"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(secrets.Items) != tt.wantV3Releases {
+				t.Errorf("%d Helm v3 release versions are created, want %d", len(secrets.Items), tt.wantV3Releases)
+			}
+		})
+	}
+}
+
+// TestConvertSQL converts a release into the Helm v3 SQL storage of a PostgreSQL database. It is
+// skipped unless the connection string of the database is set in HELM_2TO3_TEST_POSTGRES.
+func TestConvertSQL(t *testing.T) {
+	connection := os.Getenv("HELM_2TO3_TEST_POSTGRES")
+	if connection == "" {
+		t.Skip("HELM_2TO3_TEST_POSTGRES is not set to the connection string of a PostgreSQL database")
+	}
+	// The release name is unique so that runs against the same database do not collide
+	releaseName := fmt.Sprintf("app-%d", time.Now().UnixNano())
+	setFakeClientSet(t,
+		newStorageConfigMap(t, newV2Release(releaseName, 1, v2rel.Status_SUPERSEDED)),
+		newStorageConfigMap(t, newV2Release(releaseName, 2, v2rel.Status_DEPLOYED)),
+	)
+	convertOptions := newConvertOptions(releaseName)
+	convertOptions.V3Driver = v3.DriverSQL
+	convertOptions.V3SQLConnection = connection
+
+	if err := Convert(convertOptions, common.KubeConfig{}); err != nil {
+		t.Fatalf("Convert() failed: %s", err)
+	}
+	history, err := v3.GetReleaseHistory(releaseName, "default", common.KubeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, rel := range history {
+			if err := v3.DeleteRelease(rel, common.KubeConfig{}); err != nil {
+				t.Errorf("failed to delete Helm v3 release version %d: %s", rel.Version, err)
+			}
+		}
+	})
+
+	statuses := map[int]string{}
+	for _, rel := range history {
+		statuses[rel.Version] = rel.Info.Status.String()
+	}
+	want := map[int]string{1: "superseded", 2: "deployed"}
+	if fmt.Sprint(statuses) != fmt.Sprint(want) {
+		t.Errorf("Helm v3 release versions in the SQL storage are %v, want %v", statuses, want)
+	}
+}
+
+func TestSetV3StorageDriver(t *testing.T) {
+	tests := []struct {
+		name          string
+		driver        string
+		connection    string
+		envDriver     string
+		envConnection string
+		storageType   string
+		wantErr       bool
+		resource      string
+	}{
+		{name: "flag", driver: "configmap", envDriver: "secret", storageType: "secrets", resource: "configmaps"},
+		{name: "environment", envDriver: "configmap", storageType: "secrets", resource: "configmaps"},
+		{name: "v2 configmaps", storageType: "configmaps", resource: "configmaps"},
+		{name: "v2 secrets", storageType: "secrets", resource: "secrets"},
+		{name: "sql with flag connection", driver: "sql", connection: "host=localhost", resource: ""},
+		{name: "sql with environment connection", envDriver: "sql", envConnection: "host=localhost", resource: ""},
+		{name: "sql without connection", driver: "sql", wantErr: true},
+		{name: "unknown", driver: "memory", wantErr: true},
+	}
+	t.Cleanup(func() {
+		v3.SetStorageDriver(v3.DriverSecret, "")
+	})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HELM_DRIVER", tt.envDriver)
+			t.Setenv("HELM_DRIVER_SQL_CONNECTION_STRING", tt.envConnection)
+			v3.SetStorageDriver(v3.DriverSecret, "")
+
+			// The Helm v2 storage type is taken from the options as Tiller is out of the cluster
+			retrieveOptions := v2.RetrieveOptions{
+				StorageType:      tt.storageType,
+				TillerNamespace:  "kube-system",
+				TillerOutCluster: true,
+			}
+			err := setV3StorageDriver(tt.driver, tt.connection, retrieveOptions, common.KubeConfig{})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("setV3StorageDriver() succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("setV3StorageDriver() failed: %s", err)
+			}
+			if resource := v3.StorageResource(); resource != tt.resource {
+				t.Errorf("StorageResource() = %q, want %q", resource, tt.resource)
+			}
+		})
+	}
+}
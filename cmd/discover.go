@@ -0,0 +1,129 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+)
+
+var (
+	discoverOutput string
+	// Shared with the cleanup and convert commands
+	allTillers bool
+)
+
+type DiscoverOptions struct {
+	Output      string
+	TillerLabel string
+}
+
+func newDiscoverCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "discover",
+		Short: "find the Tiller installations and the Helm v2 release storage in all namespaces",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runDiscover(out)
+		},
+	}
+
+	flags := cmd.Flags()
+	settings.AddClusterFlags(flags)
+
+	flags.StringVarP(&discoverOutput, "output", "o", "table", "format of the Tillers found, 'table', 'json' or 'yaml'")
+
+	return cmd
+}
+
+// addAllTillersFlag adds the flag which runs a command for all the Tillers found by discover
+func addAllTillersFlag(flags *pflag.FlagSet, usage string) {
+	flags.BoolVar(&allTillers, "all-tillers", false, usage)
+}
+
+func runDiscover(out io.Writer) error {
+	if err := validateOutput(discoverOutput); err != nil {
+		return err
+	}
+	discoverOptions := DiscoverOptions{
+		Output:      discoverOutput,
+		TillerLabel: settings.Label,
+	}
+	kubeConfig := common.KubeConfig{
+		Context: settings.KubeContext,
+		File:    settings.KubeConfigFile,
+	}
+
+	return Discover(discoverOptions, kubeConfig, out)
+}
+
+// Discover prints the Tillers found in all namespaces, from their Deployment or from the release
+// versions left in storage by a removed Tiller. For each Tiller, it shows the namespace, the
+// Deployment and version, the storage type and the number of releases and release versions.
+func Discover(discoverOptions DiscoverOptions, kubeConfig common.KubeConfig, out io.Writer) error {
+	tillers, err := v2.DiscoverTillers(discoverOptions.TillerLabel, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	if discoverOptions.Output != "table" {
+		return printStructuredOutput(tillers, discoverOptions.Output, out)
+	}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tDEPLOYMENT\tVERSION\tSTORAGE\tRELEASES\tVERSIONS\tORPHANED")
+	for _, tiller := range tillers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\t%d\t%s\n", tiller.Namespace, formatStatus(tiller.Deployment), formatStatus(tiller.Version),
+			tiller.StorageType, tiller.Releases, tiller.ReleaseVersions, formatPresence(tiller.Orphaned))
+	}
+	return w.Flush()
+}
+
+// discoverTillers returns the Tillers found in all namespaces, after logging them
+func discoverTillers(tillerLabel string, kubeConfig common.KubeConfig) ([]v2.Tiller, error) {
+	tillers, err := v2.DiscoverTillers(tillerLabel, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("[Helm 2] %d Tillers found.\n", len(tillers))
+	for _, tiller := range tillers {
+		if tiller.Orphaned {
+			logger.Infof("[Helm 2] Orphaned Tiller storage in namespace \"%s\": %d releases in %s.\n", tiller.Namespace, tiller.Releases, tiller.StorageType)
+		} else {
+			logger.Infof("[Helm 2] Tiller \"%s\" %s in namespace \"%s\": %d releases in %s.\n", tiller.Deployment, tiller.Version, tiller.Namespace, tiller.Releases, tiller.StorageType)
+		}
+	}
+	return tillers, nil
+}
+
+// tillerRetrieveOptions returns the options to read the Helm v2 storage of a discovered Tiller. The
+// storage type is known from the discovery, so the Tiller pod is not needed.
+func tillerRetrieveOptions(tiller v2.Tiller, tillerLabel string) v2.RetrieveOptions {
+	return v2.RetrieveOptions{
+		TillerNamespace:  tiller.Namespace,
+		TillerLabel:      tillerLabel,
+		TillerOutCluster: true,
+		StorageType:      tiller.StorageType,
+	}
+}
@@ -23,6 +23,8 @@ type EnvSettings struct {
 	KubeConfigFile   string
 	KubeContext      string
 	Label            string
+	LogFormat        string
+	LogLevel         string
 	ReleaseStorage   string
 	TillerNamespace  string
 	TillerOutCluster bool
@@ -36,16 +38,23 @@ func New() *EnvSettings {
 // AddBaseFlags binds base flags to the given flagset.
 func (s *EnvSettings) AddBaseFlags(fs *pflag.FlagSet) {
 	fs.BoolVar(&s.DryRun, "dry-run", false, "simulate a command")
+	fs.StringVar(&s.LogFormat, "log-format", "text", "format of the log messages, 'text' or 'json'. The json format adds the release, version, namespace and operation as fields")
+	fs.StringVar(&s.LogLevel, "log-level", "info", "minimum level of the log messages, 'debug', 'info', 'warn' or 'error'")
 }
 
-// AddFlags binds flags to the given flagset.
-func (s *EnvSettings) AddFlags(fs *pflag.FlagSet) {
+// AddClusterFlags binds base flags and cluster access flags to the given flagset.
+func (s *EnvSettings) AddClusterFlags(fs *pflag.FlagSet) {
 	s.AddBaseFlags(fs)
 	fs.StringVar(&s.KubeConfigFile, "kubeconfig", "", "path to the kubeconfig file")
 	fs.StringVar(&s.KubeContext, "kube-context", s.KubeContext, "name of the kubeconfig context to use")
-	fs.StringVarP(&s.TillerNamespace, "tiller-ns", "t", "kube-system", "namespace of Tiller")
 	fs.StringVarP(&s.Label, "label", "l", "OWNER=TILLER", "label to select Tiller resources by")
+}
+
+// AddFlags binds flags to the given flagset.
+func (s *EnvSettings) AddFlags(fs *pflag.FlagSet) {
+	s.AddClusterFlags(fs)
+	fs.StringVarP(&s.TillerNamespace, "tiller-ns", "t", "kube-system", "namespace of Tiller")
 	fs.BoolVar(&s.TillerOutCluster, "tiller-out-cluster", false, "when  Tiller is not running in the cluster e.g. Tillerless")
-	fs.StringVarP(&s.ReleaseStorage, "release-storage", "s", "secrets", "v2 release storage type/object. It can be 'secrets' or 'configmaps'. This is only used with the 'tiller-out-cluster' flag")
+	fs.StringVarP(&s.ReleaseStorage, "release-storage", "s", "secrets", "v2 release storage type/object. It can be 'secrets' or 'configmaps', which is only used with the 'tiller-out-cluster' flag, 'auto' to detect it from the release versions found in the Tiller namespace, or 'both' to merge the release versions stored in ConfigMaps and in Secrets")
 
 }
@@ -0,0 +1,204 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+	v3 "github.com/helm/helm-2to3/pkg/v3"
+)
+
+// States of the Helm v3 release of the same name as a Helm v2 release
+const (
+	v3ReleaseExists  = "exists"
+	v3ReleaseMissing = "missing"
+	v3ReleaseUnknown = "unknown"
+)
+
+var (
+	listOutput string
+)
+
+type ListOptions struct {
+	Output           string
+	StorageType      string
+	TillerLabel      string
+	TillerNamespace  string
+	TillerOutCluster bool
+	V3Driver         string
+	V3SQLConnection  string
+}
+
+// ListedRelease is a Helm v2 release and the state of its migration to Helm v3
+type ListedRelease struct {
+	v2.ReleaseSummary
+	// V3Release is 'exists' if a Helm v3 release of the same name exists in the release namespace,
+	// 'missing' if not, or 'unknown' if the Helm v3 storage could not be read
+	V3Release string `json:"v3Release"`
+}
+
+func newListCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "list the Helm v2 releases managed by Tiller and whether they exist in Helm v3",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runList(out)
+		},
+	}
+
+	flags := cmd.Flags()
+	settings.AddFlags(flags)
+
+	flags.StringVarP(&listOutput, "output", "o", "table", "format of the list, 'table', 'json' or 'yaml'")
+	addV3DriverFlags(flags)
+
+	return cmd
+}
+
+func runList(out io.Writer) error {
+	if err := v2.ValidateStorageType(settings.ReleaseStorage); err != nil {
+		return err
+	}
+	if err := validateOutput(listOutput); err != nil {
+		return err
+	}
+	listOptions := ListOptions{
+		Output:           listOutput,
+		StorageType:      settings.ReleaseStorage,
+		TillerLabel:      settings.Label,
+		TillerNamespace:  settings.TillerNamespace,
+		TillerOutCluster: settings.TillerOutCluster,
+		V3Driver:         v3Driver,
+		V3SQLConnection:  v3SQLConnection,
+	}
+	kubeConfig := common.KubeConfig{
+		Context: settings.KubeContext,
+		File:    settings.KubeConfigFile,
+	}
+
+	return List(listOptions, kubeConfig, out)
+}
+
+// List prints the Helm v2 releases read from the Tiller storage, with the namespace, chart,
+// app version and status of their latest version, their number of versions and when they were
+// last deployed. It also shows if a Helm v3 release of the same name exists in the release
+// namespace. The releases are sorted by name.
+func List(listOptions ListOptions, kubeConfig common.KubeConfig, out io.Writer) error {
+	retrieveOptions := v2.RetrieveOptions{
+		TillerNamespace:  listOptions.TillerNamespace,
+		TillerLabel:      listOptions.TillerLabel,
+		TillerOutCluster: listOptions.TillerOutCluster,
+		StorageType:      listOptions.StorageType,
+	}
+	if err := setV3StorageDriver(listOptions.V3Driver, listOptions.V3SQLConnection, retrieveOptions, kubeConfig); err != nil {
+		return err
+	}
+	v2Releases, err := v2.GetAllReleaseVersions(retrieveOptions, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	releaseNames := []string{}
+	for releaseName := range v2Releases {
+		releaseNames = append(releaseNames, releaseName)
+	}
+	sort.Strings(releaseNames)
+
+	releases := []ListedRelease{}
+	for _, releaseName := range releaseNames {
+		summary := v2.SummarizeRelease(v2Releases[releaseName])
+		releases = append(releases, ListedRelease{
+			ReleaseSummary: summary,
+			V3Release:      getV3ReleaseState(summary.Name, summary.Namespace, kubeConfig),
+		})
+	}
+
+	return printListedReleases(releases, listOptions.Output, out)
+}
+
+// getV3ReleaseState returns if a Helm v3 release exists in a namespace
+func getV3ReleaseState(releaseName, namespace string, kubeConfig common.KubeConfig) string {
+	history, err := v3.GetReleaseHistory(releaseName, namespace, kubeConfig)
+	if err != nil {
+		logger.With(logger.Fields{logger.FieldRelease: releaseName, logger.FieldNamespace: namespace}).Warnf("[Helm 3] Failed to read release \"%s\" in namespace \"%s\" due to the following error: %s", releaseName, namespace, err)
+		return v3ReleaseUnknown
+	}
+	if len(history) > 0 {
+		return v3ReleaseExists
+	}
+	return v3ReleaseMissing
+}
+
+func printListedReleases(releases []ListedRelease, output string, out io.Writer) error {
+	if output != "table" {
+		return printStructuredOutput(releases, output, out)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tNAMESPACE\tCHART\tAPP VERSION\tSTATUS\tVERSIONS\tLAST DEPLOYED\tHELM V3")
+	for _, release := range releases {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\t%s\n", release.Name, release.Namespace, release.Chart, release.AppVersion,
+			release.Status, release.Versions, formatTime(release.LastDeployed), release.V3Release)
+	}
+	return w.Flush()
+}
+
+// validateOutput returns an error if an output format is not 'table', 'json' or 'yaml'
+func validateOutput(output string) error {
+	if output != "table" && output != "json" && output != "yaml" {
+		return fmt.Errorf("output format \"%s\" is not supported, it needs to be 'table', 'json' or 'yaml'", output)
+	}
+	return nil
+}
+
+// printStructuredOutput prints a value in a machine-readable format, 'json' or 'yaml'
+func printStructuredOutput(value interface{}, output string, out io.Writer) error {
+	if output == "yaml" {
+		data, err := yaml.Marshal(value)
+		if err != nil {
+			return err
+		}
+		_, err = out.Write(data)
+		return err
+	}
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(out, string(data))
+	return err
+}
+
+// formatTime formats a time as Helm v2 does, or returns an empty string for the zero time
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.ANSIC)
+}
@@ -19,10 +19,10 @@ package cmd
 import (
 	"errors"
 	"io"
-	"log"
 
 	"github.com/spf13/cobra"
 
+	"github.com/helm/helm-2to3/pkg/logger"
 	utils "github.com/helm/helm-2to3/pkg/utils"
 )
 
@@ -58,19 +58,20 @@ func runMove(cmd *cobra.Command, args []string) error {
 // Moves/copies v2 configuration to v2 configuration. It copies repository config,
 // plugins and starters. It does not copy cache.
 func Move(dryRun bool) error {
+	moveLog := logger.With(logger.Fields{logger.FieldOperation: "move-config"})
 	var err error
 	var doConfig bool
 	if dryRun {
-		log.Println("NOTE: This is in dry-run mode, the following actions will not be executed.")
-		log.Println("Run without --dry-run to take the actions described below:")
-		log.Println()
+		moveLog.Infof("NOTE: This is in dry-run mode, the following actions will not be executed.")
+		moveLog.Infof("Run without --dry-run to take the actions described below:")
+		moveLog.Infof("")
 	}
 
-	log.Println("WARNING: Helm v3 configuration may be overwritten during this operation.")
-	log.Println()
+	moveLog.Warnf("WARNING: Helm v3 configuration may be overwritten during this operation.")
+	moveLog.Infof("")
 	if skipConfirmation {
 
-		log.Println("Skipping confirmation before performing move configuration.")
+		moveLog.Infof("Skipping confirmation before performing move configuration.")
 		doConfig = true
 	} else {
 		doConfig, err = utils.AskConfirmation("Move config", "move the v2 configuration")
@@ -79,17 +80,17 @@ func Move(dryRun bool) error {
 		}
 	}
 	if !doConfig {
-		log.Println("Move will not proceed as the user didn't answer (Y|y) in order to continue.")
+		moveLog.Infof("Move will not proceed as the user didn't answer (Y|y) in order to continue.")
 		return nil
 	}
 
-	log.Println("\nHelm v2 configuration will be moved to Helm v3 configuration.")
+	moveLog.Infof("\nHelm v2 configuration will be moved to Helm v3 configuration.")
 	err = utils.Copyv2HomeTov3(dryRun)
 	if err != nil {
 		return err
 	}
 	if !dryRun {
-		log.Println("Helm v2 configuration was moved successfully to Helm v3 configuration.")
+		moveLog.Infof("Helm v2 configuration was moved successfully to Helm v3 configuration.")
 	}
 	return nil
 }
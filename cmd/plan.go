@@ -0,0 +1,114 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/pflag"
+	"helm.sh/helm/v3/pkg/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/plan"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+)
+
+var (
+	// Shared with the cleanup and convert commands
+	planOutput string
+)
+
+// addPlanFlags adds the flags which write the plan of a dry run
+func addPlanFlags(flags *pflag.FlagSet) {
+	flags.StringVarP(&planOutput, "output", "o", "", "with --dry-run, write the plan of the actions to stdout in a machine-readable format, 'json' or 'yaml'")
+}
+
+// validatePlanOutput returns an error if the plan output flag is set without dry run or
+// to an unsupported format
+func validatePlanOutput(output string, dryRun bool) error {
+	if output == "" {
+		return nil
+	}
+	if !dryRun {
+		return errors.New("output flag can only be used with --dry-run")
+	}
+	return plan.ValidateFormat(output)
+}
+
+// newPlan returns a plan for a command if the plan output is set, or nil otherwise
+func newPlan(command, output string) *plan.Plan {
+	if output == "" {
+		return nil
+	}
+	return plan.New(command)
+}
+
+// addReleaseVersionToPlan adds a Helm v3 release version to create to a plan
+func addReleaseVersionToPlan(p *plan.Plan, v2ReleaseName string, v3Release *release.Release) {
+	var chart string
+	if v3Release.Chart != nil && v3Release.Chart.Metadata != nil {
+		chart = fmt.Sprintf("%s-%s", v3Release.Chart.Metadata.Name, v3Release.Chart.Metadata.Version)
+	}
+	p.AddReleaseVersion(plan.ReleaseVersion{
+		V2Release: v2ReleaseName,
+		Name:      v3Release.Name,
+		Namespace: v3Release.Namespace,
+		Version:   int32(v3Release.Version),
+		Status:    v3Release.Info.Status.String(),
+		Chart:     chart,
+	})
+}
+
+// addStorageObjectsToPlan adds the Helm v2 storage objects of release versions to delete to a plan
+func addStorageObjectsToPlan(p *plan.Plan, retrieveOptions v2.RetrieveOptions, releaseName string, versions []int32, kubeConfig common.KubeConfig) error {
+	if p == nil || len(versions) <= 0 {
+		return nil
+	}
+	storage, err := v2.GetStorage(retrieveOptions, kubeConfig)
+	if err != nil {
+		return err
+	}
+	namespace := retrieveOptions.TillerNamespace
+	if namespace == "" {
+		namespace = "kube-system"
+	}
+	for _, version := range versions {
+		name := v2.GetReleaseVersionName(releaseName, version)
+		var kinds []string
+		switch storage := storage.(type) {
+		case *v2.MergedStorage:
+			// The release version may be in a ConfigMap, a Secret or both
+			if kinds, err = storage.Kinds(name); err != nil {
+				return err
+			}
+		default:
+			kinds = []string{"ConfigMap"}
+			if storage.Type() == v2.StorageSecrets {
+				kinds = []string{"Secret"}
+			}
+		}
+		for _, kind := range kinds {
+			p.AddStorageObject(plan.Object{
+				Kind:      kind,
+				Namespace: namespace,
+				Name:      name,
+			})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	v2rel "k8s.io/helm/pkg/proto/hapi/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/preflight"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+	v3 "github.com/helm/helm-2to3/pkg/v3"
+)
+
+var (
+	preflightAll bool
+)
+
+type PreflightOptions struct {
+	AdoptResources   bool
+	AllReleases      bool
+	ReleaseName      string
+	Renames          map[string]string
+	StorageType      string
+	TargetNamespace  string
+	TillerLabel      string
+	TillerNamespace  string
+	TillerOutCluster bool
+	V3Driver         string
+	V3SQLConnection  string
+}
+
+func newPreflightCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "preflight [flags] RELEASE|--all",
+		Short: "check that Helm v2 releases can be migrated to Helm v3 without writing anything",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if preflightAll {
+				if len(args) != 0 {
+					return errors.New("name of release cannot be defined when all releases are checked")
+				}
+				return nil
+			}
+			if len(args) != 1 {
+				return errors.New("name of release to be checked has to be defined")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runPreflight(out, args)
+		},
+	}
+
+	flags := cmd.Flags()
+	settings.AddFlags(flags)
+
+	flags.BoolVar(&adoptResources, "adopt-resources", false, "set if the releases will be converted with --adopt-resources, to check the permissions to adopt their resources")
+	flags.BoolVar(&preflightAll, "all", false, "check all Helm v2 releases managed by Tiller")
+	flags.StringToStringVar(&renames, "rename", map[string]string{}, "set if the releases will be converted with --rename")
+	flags.StringVar(&renameFile, "rename-file", "", "set if the releases will be converted with --rename-file")
+	flags.StringVar(&targetNamespace, "target-namespace", "", "set if the releases will be converted with --target-namespace")
+	addV3DriverFlags(flags)
+
+	return cmd
+}
+
+func runPreflight(out io.Writer, args []string) error {
+	var releaseName string
+	if !preflightAll {
+		releaseName = args[0]
+	}
+	if err := v2.ValidateStorageType(settings.ReleaseStorage); err != nil {
+		return err
+	}
+	releaseRenames, err := getRenames()
+	if err != nil {
+		return err
+	}
+	preflightOptions := PreflightOptions{
+		AdoptResources:   adoptResources,
+		AllReleases:      preflightAll,
+		ReleaseName:      releaseName,
+		Renames:          releaseRenames,
+		StorageType:      settings.ReleaseStorage,
+		TargetNamespace:  targetNamespace,
+		TillerLabel:      settings.Label,
+		TillerNamespace:  settings.TillerNamespace,
+		TillerOutCluster: settings.TillerOutCluster,
+		V3Driver:         v3Driver,
+		V3SQLConnection:  v3SQLConnection,
+	}
+	kubeConfig := common.KubeConfig{
+		Context: settings.KubeContext,
+		File:    settings.KubeConfigFile,
+	}
+
+	return Preflight(preflightOptions, kubeConfig, out)
+}
+
+// Preflight validates that Helm v2 releases can be converted and cleaned up, without writing
+// anything to the cluster. It checks the access to the Tiller storage, the existence of the
+// release namespaces, the RBAC permissions needed by convert and cleanup and that the releases
+// do not already exist in Helm v3, under their Helm v3 name and namespace. With adoption, it also
+// checks the permissions to adopt the resources of the deployed version of each release. It
+// returns an error if any check failed.
+func Preflight(preflightOptions PreflightOptions, kubeConfig common.KubeConfig, out io.Writer) error {
+	results := []preflight.Result{}
+	clientSet := v2.GetClientSet(kubeConfig)
+
+	retrieveOptions := v2.RetrieveOptions{
+		ReleaseName:      preflightOptions.ReleaseName,
+		TillerNamespace:  preflightOptions.TillerNamespace,
+		TillerLabel:      preflightOptions.TillerLabel,
+		TillerOutCluster: preflightOptions.TillerOutCluster,
+		StorageType:      preflightOptions.StorageType,
+	}
+	storageType, err := v2.GetStorageType(retrieveOptions, kubeConfig)
+	if err != nil {
+		results = append(results, preflight.Fail("tiller-storage", preflightOptions.TillerNamespace, fmt.Sprintf("failed to detect the storage type: %s", err)))
+		return printPreflightResults(results, out)
+	}
+	if err := setV3StorageDriver(preflightOptions.V3Driver, preflightOptions.V3SQLConnection, retrieveOptions, kubeConfig); err != nil {
+		return err
+	}
+	storageTarget := fmt.Sprintf("%s in %s", storageType, preflightOptions.TillerNamespace)
+
+	// Permissions on the Helm v2 storage and Tiller, which are needed by convert and cleanup
+	storageResources := []string{storageType}
+	if storageType == v2.StorageBoth {
+		storageResources = []string{v2.StorageConfigMaps, v2.StorageSecrets}
+	}
+	permissions := []preflight.Permission{}
+	for _, resource := range storageResources {
+		permissions = append(permissions,
+			preflight.Permission{Namespace: preflightOptions.TillerNamespace, Resource: resource, Verb: "list", Severity: preflight.StatusFail},
+			preflight.Permission{Namespace: preflightOptions.TillerNamespace, Resource: resource, Verb: "get", Severity: preflight.StatusFail},
+			preflight.Permission{Namespace: preflightOptions.TillerNamespace, Resource: resource, Verb: "delete", Severity: preflight.StatusWarn},
+		)
+	}
+	if !preflightOptions.TillerOutCluster {
+		permissions = append(permissions,
+			preflight.Permission{Namespace: preflightOptions.TillerNamespace, Resource: "pods", Verb: "list", Severity: preflight.StatusFail},
+			preflight.Permission{Namespace: preflightOptions.TillerNamespace, Group: "apps", Resource: "deployments", Verb: "delete", Severity: preflight.StatusWarn},
+			preflight.Permission{Namespace: preflightOptions.TillerNamespace, Resource: "services", Verb: "delete", Severity: preflight.StatusWarn},
+		)
+	}
+	for _, permission := range permissions {
+		results = append(results, preflight.CheckPermission(clientSet, permission))
+	}
+
+	var v2Releases map[string][]*v2rel.Release
+	if preflightOptions.AllReleases {
+		v2Releases, err = v2.GetAllReleaseVersions(retrieveOptions, kubeConfig)
+	} else {
+		var versions []*v2rel.Release
+		versions, err = v2.GetReleaseVersions(retrieveOptions, kubeConfig)
+		v2Releases = map[string][]*v2rel.Release{preflightOptions.ReleaseName: versions}
+	}
+	if err != nil {
+		results = append(results, preflight.Fail("tiller-storage", storageTarget, fmt.Sprintf("failed to read releases: %s", err)))
+		return printPreflightResults(results, out)
+	}
+	results = append(results, preflight.Pass("tiller-storage", storageTarget, fmt.Sprintf("%d releases found", len(v2Releases))))
+
+	releaseNames := []string{}
+	namespaces := map[string]bool{}
+	for releaseName, versions := range v2Releases {
+		releaseNames = append(releaseNames, releaseName)
+		for _, version := range versions {
+			namespaces[version.Namespace] = true
+		}
+	}
+	if preflightOptions.TargetNamespace != "" {
+		// The Helm v3 releases are all stored in the target namespace
+		namespaces = map[string]bool{preflightOptions.TargetNamespace: true}
+	}
+	sort.Strings(releaseNames)
+	releaseNamespaces := []string{}
+	for namespace := range namespaces {
+		releaseNamespaces = append(releaseNamespaces, namespace)
+	}
+	sort.Strings(releaseNamespaces)
+
+	// Release namespaces and permissions on the Helm v3 storage, which are needed by convert
+	v3StorageResource := v3.StorageResource()
+	for _, namespace := range releaseNamespaces {
+		results = append(results, preflight.CheckNamespace(clientSet, namespace))
+		if v3StorageResource == "" {
+			continue
+		}
+		for _, verb := range []string{"list", "get", "create"} {
+			results = append(results, preflight.CheckPermission(clientSet, preflight.Permission{Namespace: namespace, Resource: v3StorageResource, Verb: verb, Severity: preflight.StatusFail}))
+		}
+		// Delete is only needed to roll back a failed conversion
+		results = append(results, preflight.CheckPermission(clientSet, preflight.Permission{Namespace: namespace, Resource: v3StorageResource, Verb: "delete", Severity: preflight.StatusWarn}))
+	}
+
+	// Existing Helm v3 releases, under the name and namespace the releases are converted into
+	convertOptions := ConvertOptions{Renames: preflightOptions.Renames, TargetNamespace: preflightOptions.TargetNamespace}
+	for _, releaseName := range releaseNames {
+		versions := v2Releases[releaseName]
+		if len(versions) <= 0 {
+			results = append(results, preflight.Fail("v2-release", releaseName, "release has no versions"))
+			continue
+		}
+		convertOptions.ReleaseName = releaseName
+		v3ReleaseName := getV3ReleaseName(convertOptions)
+		namespace := versions[len(versions)-1].Namespace
+		if preflightOptions.TargetNamespace != "" {
+			namespace = preflightOptions.TargetNamespace
+		}
+		target := fmt.Sprintf("%s in %s", v3ReleaseName, namespace)
+		history, err := v3.GetReleaseHistory(v3ReleaseName, namespace, kubeConfig)
+		switch {
+		case err != nil:
+			results = append(results, preflight.Fail("v3-release", target, fmt.Sprintf("failed to read Helm v3 releases: %s", err)))
+		case len(history) > 0:
+			results = append(results, preflight.Fail("v3-release", target, fmt.Sprintf("Helm v3 release already exists with %d versions", len(history))))
+		default:
+			results = append(results, preflight.Pass("v3-release", target, "Helm v3 release does not exist"))
+		}
+
+		// Permissions to adopt the live resources, which are the ones of the deployed version
+		if preflightOptions.AdoptResources {
+			results = append(results, preflight.CheckAdoptPermissions(clientSet, getDeployedVersion(versions).Manifest, namespace)...)
+		}
+	}
+
+	return printPreflightResults(results, out)
+}
+
+func printPreflightResults(results []preflight.Result, out io.Writer) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHECK\tTARGET\tRESULT\tMESSAGE")
+	for _, result := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", result.Check, result.Target, result.Status, result.Message)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	if preflight.HasFailures(results) {
+		return errors.New("preflight checks failed")
+	}
+	return nil
+}
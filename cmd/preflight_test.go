@@ -0,0 +1,110 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	authv1 "k8s.io/api/authorization/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+	v2rel "k8s.io/helm/pkg/proto/hapi/release"
+
+	"helm.sh/helm/v3/pkg/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	v3 "github.com/helm/helm-2to3/pkg/v3"
+)
+
+// allowAllBut makes the access reviews of a fake client set allow every permission except one,
+// as "verb resource.group"
+func allowAllBut(cs *fake.Clientset, denied string) {
+	cs.PrependReactor("create", "selfsubjectaccessreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		review := action.(k8stesting.CreateAction).GetObject().(*authv1.SelfSubjectAccessReview)
+		attributes := review.Spec.ResourceAttributes
+		permission := attributes.Verb + " " + attributes.Resource
+		if attributes.Group != "" {
+			permission += "." + attributes.Group
+		}
+		review.Status.Allowed = permission != denied
+		return true, review, nil
+	})
+}
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name            string
+		adoptResources  bool
+		renames         map[string]string
+		targetNamespace string
+		wantErr         bool
+		wantOutput      string
+	}{
+		{name: "without adoption", wantOutput: "v3-release app in default PASS"},
+		{name: "with adoption", adoptResources: true, wantErr: true, wantOutput: "rbac patch deployments.apps in default FAIL"},
+		{name: "existing renamed release", renames: map[string]string{"app": "web"}, targetNamespace: "web", wantErr: true, wantOutput: "v3-release web in web FAIL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v2Release := newV2Release("app", 1, v2rel.Status_DEPLOYED)
+			v2Release.Manifest += "---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: app\n"
+			cs := setFakeClientSet(t, newStorageConfigMap(t, v2Release)).(*fake.Clientset)
+			cs.Discovery().(*fakediscovery.FakeDiscovery).Resources = []*metav1.APIResourceList{
+				{GroupVersion: "v1", APIResources: []metav1.APIResource{{Name: "configmaps", Kind: "ConfigMap", Namespaced: true}}},
+				{GroupVersion: "apps/v1", APIResources: []metav1.APIResource{{Name: "deployments", Kind: "Deployment", Namespaced: true}}},
+			}
+			allowAllBut(cs, "patch deployments.apps")
+			for _, namespace := range []string{"default", "web"} {
+				if _, err := cs.CoreV1().Namespaces().Create(context.Background(), &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}, metav1.CreateOptions{}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// A Helm v3 release "web" already exists in namespace "web"
+			existing := &release.Release{Name: "web", Namespace: "web", Version: 1, Info: &release.Info{Status: release.StatusDeployed}}
+			if err := v3.StoreRelease(existing, common.KubeConfig{}); err != nil {
+				t.Fatal(err)
+			}
+
+			preflightOptions := PreflightOptions{
+				AdoptResources:   tt.adoptResources,
+				ReleaseName:      "app",
+				Renames:          tt.renames,
+				StorageType:      "configmaps",
+				TargetNamespace:  tt.targetNamespace,
+				TillerLabel:      "OWNER=TILLER",
+				TillerNamespace:  "kube-system",
+				TillerOutCluster: true,
+				V3Driver:         "secret",
+			}
+			var out bytes.Buffer
+			err := Preflight(preflightOptions, common.KubeConfig{}, &out)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Preflight() error = %v, want error %t\n%s", err, tt.wantErr, out.String())
+			}
+			if !strings.Contains(strings.Join(strings.Fields(out.String()), " "), tt.wantOutput) {
+				t.Errorf("Preflight() output does not contain %q:\n%s", tt.wantOutput, out.String())
+			}
+		})
+	}
+}
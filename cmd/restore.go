@@ -0,0 +1,147 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
+
+	"github.com/helm/helm-2to3/pkg/backup"
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+)
+
+var (
+	overwrite bool
+)
+
+type RestoreOptions struct {
+	ArchiveFile string
+	DryRun      bool
+	Overwrite   bool
+	// TillerNamespace overrides the Tiller namespace of the archive when set
+	TillerNamespace string
+}
+
+func newRestoreCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "restore [flags] ARCHIVE",
+		Short: "restore Helm v2 release data from a backup archive",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("backup archive to be restored has to be defined")
+			}
+			return nil
+		},
+		RunE: runRestore,
+	}
+
+	flags := cmd.Flags()
+	settings.AddFlags(flags)
+
+	flags.BoolVar(&overwrite, "overwrite", false, "if set, release versions which already exist in Helm v2 storage are overwritten instead of being skipped")
+
+	return cmd
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	restoreOptions := RestoreOptions{
+		ArchiveFile: args[0],
+		DryRun:      settings.DryRun,
+		Overwrite:   overwrite,
+	}
+	if cmd.Flags().Changed("tiller-ns") {
+		restoreOptions.TillerNamespace = settings.TillerNamespace
+	}
+	kubeConfig := common.KubeConfig{
+		Context: settings.KubeContext,
+		File:    settings.KubeConfigFile,
+	}
+
+	return Restore(restoreOptions, kubeConfig)
+}
+
+// Restore recreates the Helm v2 storage objects of a backup archive in the Tiller namespace, with
+// their original labels. The checksums of the archive are validated before anything is written.
+// Release versions which already exist are skipped unless they are to be overwritten.
+func Restore(restoreOptions RestoreOptions, kubeConfig common.KubeConfig) error {
+	restoreLog := logger.With(logger.Fields{logger.FieldOperation: "restore"})
+	if restoreOptions.DryRun {
+		restoreLog.Infof("NOTE: This is in dry-run mode, the following actions will not be executed.")
+		restoreLog.Infof("Run without --dry-run to take the actions described below:")
+		restoreLog.Infof("")
+	}
+
+	file, err := os.Open(restoreOptions.ArchiveFile)
+	if err != nil {
+		return fmt.Errorf("Failed to open backup archive \"%s\" due to the following error: %s", restoreOptions.ArchiveFile, err)
+	}
+	defer file.Close()
+	manifest, objects, err := backup.Read(file)
+	if err != nil {
+		return fmt.Errorf("Failed to read backup archive \"%s\" due to the following error: %s", restoreOptions.ArchiveFile, err)
+	}
+
+	namespace := manifest.TillerNamespace
+	if restoreOptions.TillerNamespace != "" {
+		namespace = restoreOptions.TillerNamespace
+	}
+	restoreLog.Infof("[Helm 2] %d release versions captured at %s from %s in namespace \"%s\" will be restored to namespace \"%s\".\n",
+		len(objects), manifest.CaptureTime.Format("2006-01-02 15:04:05 MST"), manifest.StorageType, manifest.TillerNamespace, namespace)
+
+	restored := 0
+	for _, object := range objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return err
+		}
+		relVerName := accessor.GetName()
+		exists, err := v2.RestoreStorageObject(namespace, object, restoreOptions.Overwrite, restoreOptions.DryRun, kubeConfig)
+		if err != nil {
+			return fmt.Errorf("[Helm 2] ReleaseVersion \"%s\" failed to restore with error: %s", relVerName, err)
+		}
+		if exists && !restoreOptions.Overwrite {
+			restoreLog.Warnf("[Helm 2] ReleaseVersion \"%s\" already exists, it will be skipped.\n", relVerName)
+			continue
+		}
+		restored++
+		if restoreOptions.DryRun {
+			if exists {
+				restoreLog.Infof("[Helm 2] ReleaseVersion \"%s\" will be overwritten.\n", relVerName)
+			} else {
+				restoreLog.Infof("[Helm 2] ReleaseVersion \"%s\" will be restored.\n", relVerName)
+			}
+			continue
+		}
+		if exists {
+			restoreLog.Infof("[Helm 2] ReleaseVersion \"%s\" overwritten.\n", relVerName)
+		} else {
+			restoreLog.Infof("[Helm 2] ReleaseVersion \"%s\" restored.\n", relVerName)
+		}
+	}
+	if !restoreOptions.DryRun {
+		restoreLog.Infof("[Helm 2] %d of %d release versions restored.\n", restored, len(objects))
+	}
+
+	return nil
+}
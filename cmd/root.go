@@ -19,9 +19,12 @@ package cmd
 import (
 	"errors"
 	"io"
+	"log"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/helm/helm-2to3/pkg/logger"
 )
 
 var (
@@ -40,6 +43,9 @@ func NewRootCmd(out io.Writer, args []string) *cobra.Command {
 			}
 			return nil
 		},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return setupLogger(settings)
+		},
 	}
 
 	flags := cmd.PersistentFlags()
@@ -61,10 +67,40 @@ func NewRootCmd(out io.Writer, args []string) *cobra.Command {
 	// need to be explicitely handled here.
 
 	cmd.AddCommand(
+		newBackupCmd(out),
 		newCleanupCmd(out),
 		newConvertCmd(out),
+		newDiscoverCmd(out),
+		newListCmd(out),
 		newMoveConfigCmd(out),
+		newPreflightCmd(out),
+		newRestoreCmd(out),
+		newStatusCmd(out),
+		newVerifyCmd(out),
 	)
 
 	return cmd
 }
+
+// setupLogger sets the logger used by all commands from the log flags. Messages written with the
+// standard log package, e.g. by dependencies, go through the logger too.
+func setupLogger(settings *EnvSettings) error {
+	level := logger.LevelInfo
+	if settings.LogLevel != "" {
+		var err error
+		if level, err = logger.ParseLevel(settings.LogLevel); err != nil {
+			return err
+		}
+	}
+	format := logger.FormatText
+	if settings.LogFormat != "" {
+		if err := logger.ValidateFormat(settings.LogFormat); err != nil {
+			return err
+		}
+		format = settings.LogFormat
+	}
+	logger.SetDefault(logger.New(os.Stderr, level, format))
+	log.SetFlags(0)
+	log.SetOutput(logger.Default().Writer())
+	return nil
+}
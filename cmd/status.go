@@ -0,0 +1,274 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
+	v2rel "k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/helm/helm-2to3/pkg/checkpoint"
+	common "github.com/helm/helm-2to3/pkg/common"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+	v3 "github.com/helm/helm-2to3/pkg/v3"
+)
+
+var (
+	statusOutput string
+)
+
+type StatusOptions struct {
+	CheckpointFile   string
+	Output           string
+	ReleaseName      string
+	Renames          map[string]string
+	StorageType      string
+	TargetNamespace  string
+	TillerLabel      string
+	TillerNamespace  string
+	TillerOutCluster bool
+	V3Driver         string
+	V3SQLConnection  string
+}
+
+// ReleaseStatus is the history of a release in Helm v2 and Helm v3
+type ReleaseStatus struct {
+	Name      string `json:"name"`
+	V3Name    string `json:"v3Name"`
+	Namespace string `json:"namespace"`
+	// Versions are sorted by version
+	Versions []VersionStatus `json:"versions"`
+}
+
+// VersionStatus is the state of a release version in Helm v2 and Helm v3
+type VersionStatus struct {
+	Version  int32  `json:"version"`
+	InV2     bool   `json:"inV2"`
+	V2Status string `json:"v2Status,omitempty"`
+	InV3     bool   `json:"inV3"`
+	V3Status string `json:"v3Status,omitempty"`
+	// V2StorageDeleted is true if the version is in Helm v3 but its Helm v2 storage
+	// object was deleted, for example by convert --delete-v2-releases or cleanup. It is
+	// false for the versions created by Helm v3 after the conversion.
+	V2StorageDeleted bool `json:"v2StorageDeleted"`
+}
+
+func newStatusCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "status [flags] RELEASE",
+		Short: "show the Helm v2 and Helm v3 history of a release side by side",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("name of release has to be defined")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runStatus(out, args)
+		},
+	}
+
+	flags := cmd.Flags()
+	settings.AddFlags(flags)
+
+	flags.StringVar(&checkpointFile, "checkpoint", "", "checkpoint file of the conversion, to show the versions deleted from Helm v2 storage by convert. Without it, only versions older than the latest one left in Helm v2 are shown as deleted, so none are once all versions were deleted")
+	flags.StringVarP(&statusOutput, "output", "o", "table", "format of the status, 'table', 'json' or 'yaml'")
+	flags.StringToStringVar(&renames, "rename", map[string]string{}, "set if the release was converted with --rename")
+	flags.StringVar(&renameFile, "rename-file", "", "set if the release was converted with --rename-file")
+	flags.StringVar(&targetNamespace, "target-namespace", "", "set if the release was converted with --target-namespace. Required if the release has no versions left in Helm v2")
+	addV3DriverFlags(flags)
+
+	return cmd
+}
+
+func runStatus(out io.Writer, args []string) error {
+	if err := v2.ValidateStorageType(settings.ReleaseStorage); err != nil {
+		return err
+	}
+	if err := validateOutput(statusOutput); err != nil {
+		return err
+	}
+	releaseRenames, err := getRenames()
+	if err != nil {
+		return err
+	}
+	statusOptions := StatusOptions{
+		CheckpointFile:   checkpointFile,
+		Output:           statusOutput,
+		ReleaseName:      args[0],
+		Renames:          releaseRenames,
+		StorageType:      settings.ReleaseStorage,
+		TargetNamespace:  targetNamespace,
+		TillerLabel:      settings.Label,
+		TillerNamespace:  settings.TillerNamespace,
+		TillerOutCluster: settings.TillerOutCluster,
+		V3Driver:         v3Driver,
+		V3SQLConnection:  v3SQLConnection,
+	}
+	kubeConfig := common.KubeConfig{
+		Context: settings.KubeContext,
+		File:    settings.KubeConfigFile,
+	}
+
+	return Status(statusOptions, kubeConfig, out)
+}
+
+// Status prints the versions of a release in Helm v2 and Helm v3 side by side. For each version,
+// it shows if the version is in Helm v2 and in Helm v3 with its status on each side, and if the
+// Helm v2 storage object of a converted version was deleted. The Helm v3 release is looked up in
+// the namespace of the latest Helm v2 version, unless a target namespace is set.
+func Status(statusOptions StatusOptions, kubeConfig common.KubeConfig, out io.Writer) error {
+	retrieveOptions := v2.RetrieveOptions{
+		ReleaseName:      statusOptions.ReleaseName,
+		TillerNamespace:  statusOptions.TillerNamespace,
+		TillerLabel:      statusOptions.TillerLabel,
+		TillerOutCluster: statusOptions.TillerOutCluster,
+		StorageType:      statusOptions.StorageType,
+	}
+	if err := setV3StorageDriver(statusOptions.V3Driver, statusOptions.V3SQLConnection, retrieveOptions, kubeConfig); err != nil {
+		return err
+	}
+	// The versions of a release which was fully converted and cleaned up are all deleted from
+	// Helm v2, which is not an error here
+	v2Versions, err := v2.GetReleaseHistory(retrieveOptions, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	namespace := statusOptions.TargetNamespace
+	if namespace == "" {
+		if len(v2Versions) <= 0 {
+			return fmt.Errorf("[Helm 2] Release \"%s\" has no versions, the namespace of the Helm v3 release needs to be set with --target-namespace", statusOptions.ReleaseName)
+		}
+		namespace = v2Versions[len(v2Versions)-1].Namespace
+	}
+	v3ReleaseName := getV3ReleaseName(ConvertOptions{ReleaseName: statusOptions.ReleaseName, Renames: statusOptions.Renames})
+	v3Versions, err := v3.GetReleaseHistory(v3ReleaseName, namespace, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	var journal *checkpoint.Journal
+	if statusOptions.CheckpointFile != "" {
+		if _, err := os.Stat(statusOptions.CheckpointFile); err != nil {
+			return fmt.Errorf("Failed to read checkpoint file \"%s\" due to the following error: %s", statusOptions.CheckpointFile, err)
+		}
+		journal, err = checkpoint.Open(statusOptions.CheckpointFile, true)
+		if err != nil {
+			return err
+		}
+		defer journal.Close()
+	}
+
+	releaseStatus := mergeReleaseHistories(v2Versions, v3Versions, statusOptions.ReleaseName, journal)
+	releaseStatus.Name = statusOptions.ReleaseName
+	releaseStatus.V3Name = v3ReleaseName
+	releaseStatus.Namespace = namespace
+	if len(releaseStatus.Versions) <= 0 {
+		return fmt.Errorf("Release \"%s\" does not exist in Helm v2 or in Helm v3 in namespace \"%s\"", statusOptions.ReleaseName, namespace)
+	}
+
+	if statusOptions.Output != "table" {
+		return printStructuredOutput(releaseStatus, statusOptions.Output, out)
+	}
+	return printReleaseStatus(releaseStatus, out)
+}
+
+// mergeReleaseHistories merges the Helm v2 and Helm v3 versions of a release. A version only in
+// Helm v3 had its Helm v2 storage deleted if the checkpoint journal records it, or if it is not
+// newer than the latest version left in Helm v2. Newer versions were created by Helm v3 upgrades.
+func mergeReleaseHistories(v2Versions []*v2rel.Release, v3Versions []*release.Release, releaseName string, journal *checkpoint.Journal) ReleaseStatus {
+	versions := map[int32]*VersionStatus{}
+	getVersion := func(version int32) *VersionStatus {
+		if _, ok := versions[version]; !ok {
+			versions[version] = &VersionStatus{Version: version}
+		}
+		return versions[version]
+	}
+	var latestV2Version int32
+	for _, v2Version := range v2Versions {
+		versionStatus := getVersion(v2Version.Version)
+		versionStatus.InV2 = true
+		versionStatus.V2Status = v2.GetStatus(v2Version)
+		if v2Version.Version > latestV2Version {
+			latestV2Version = v2Version.Version
+		}
+	}
+	for _, v3Version := range v3Versions {
+		versionStatus := getVersion(int32(v3Version.Version))
+		versionStatus.InV3 = true
+		if v3Version.Info != nil {
+			versionStatus.V3Status = v3Version.Info.Status.String()
+		}
+		if !versionStatus.InV2 {
+			versionStatus.V2StorageDeleted = versionStatus.Version <= latestV2Version ||
+				journal.IsDone(releaseName, versionStatus.Version, checkpoint.StepDeleteV2)
+		}
+	}
+
+	releaseStatus := ReleaseStatus{Versions: []VersionStatus{}}
+	for _, versionStatus := range versions {
+		releaseStatus.Versions = append(releaseStatus.Versions, *versionStatus)
+	}
+	sort.Slice(releaseStatus.Versions, func(i, j int) bool {
+		return releaseStatus.Versions[i].Version < releaseStatus.Versions[j].Version
+	})
+	return releaseStatus
+}
+
+func printReleaseStatus(releaseStatus ReleaseStatus, out io.Writer) error {
+	fmt.Fprintf(out, "RELEASE: %s\n", releaseStatus.Name)
+	if releaseStatus.V3Name != releaseStatus.Name {
+		fmt.Fprintf(out, "HELM V3 RELEASE: %s\n", releaseStatus.V3Name)
+	}
+	fmt.Fprintf(out, "NAMESPACE: %s\n\n", releaseStatus.Namespace)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tHELM V2\tV2 STATUS\tHELM V3\tV3 STATUS\tV2 STORAGE")
+	for _, versionStatus := range releaseStatus.Versions {
+		v2Storage := "-"
+		if versionStatus.InV2 {
+			v2Storage = "present"
+		} else if versionStatus.V2StorageDeleted {
+			v2Storage = "deleted"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", versionStatus.Version, formatPresence(versionStatus.InV2), formatStatus(versionStatus.V2Status),
+			formatPresence(versionStatus.InV3), formatStatus(versionStatus.V3Status), v2Storage)
+	}
+	return w.Flush()
+}
+
+func formatPresence(present bool) string {
+	if present {
+		return "yes"
+	}
+	return "no"
+}
+
+func formatStatus(status string) string {
+	if status == "" {
+		return "-"
+	}
+	return status
+}
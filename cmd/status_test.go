@@ -0,0 +1,145 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8stesting "k8s.io/client-go/testing"
+	v2rel "k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/helm/helm-2to3/pkg/checkpoint"
+	"github.com/helm/helm-2to3/pkg/common"
+)
+
+func TestMergeReleaseHistories(t *testing.T) {
+	v2Versions := []*v2rel.Release{
+		{Name: "app", Version: 1, Info: &v2rel.Info{Status: &v2rel.Status{Code: v2rel.Status_SUPERSEDED}}},
+		{Name: "app", Version: 3, Info: &v2rel.Info{Status: &v2rel.Status{Code: v2rel.Status_DEPLOYED}}},
+	}
+	v3Versions := []*release.Release{}
+	for version := 2; version <= 6; version++ {
+		v3Versions = append(v3Versions, &release.Release{Name: "app", Version: version, Info: &release.Info{Status: release.StatusSuperseded}})
+	}
+
+	journal, err := checkpoint.Open(filepath.Join(t.TempDir(), "checkpoint"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+	if err := journal.Record("app", 5, checkpoint.StepDeleteV2); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		journal *checkpoint.Journal
+		deleted map[int32]bool
+	}{
+		// Version 2 is older than the latest Helm v2 version, 4 to 6 are Helm v3 upgrades
+		{name: "without checkpoint", deleted: map[int32]bool{2: true}},
+		{name: "with checkpoint", journal: journal, deleted: map[int32]bool{2: true, 5: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			releaseStatus := mergeReleaseHistories(v2Versions, v3Versions, "app", tt.journal)
+			if len(releaseStatus.Versions) != 6 {
+				t.Fatalf("mergeReleaseHistories() returned %d versions, want 6", len(releaseStatus.Versions))
+			}
+			for i, versionStatus := range releaseStatus.Versions {
+				if versionStatus.Version != int32(i+1) {
+					t.Errorf("version %d is %d, want versions sorted", i, versionStatus.Version)
+				}
+				if versionStatus.V2StorageDeleted != tt.deleted[versionStatus.Version] {
+					t.Errorf("version %d has V2StorageDeleted %t, want %t", versionStatus.Version, versionStatus.V2StorageDeleted, tt.deleted[versionStatus.Version])
+				}
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	cs := setFakeClientSet(t,
+		newStorageConfigMap(t, newV2Release("app", 1, v2rel.Status_SUPERSEDED)),
+		newStorageConfigMap(t, newV2Release("app", 2, v2rel.Status_DEPLOYED)),
+		newStorageConfigMap(t, newV2Release("web", 1, v2rel.Status_DEPLOYED)),
+	)
+	convertOptions := newConvertOptions("app")
+	convertOptions.DeleteRelease = true
+	if err := Convert(convertOptions, common.KubeConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the storage objects of the release are listed
+	var selectors []string
+	cs.(k8stesting.FakeClient).PrependReactor("list", "configmaps", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		selectors = append(selectors, action.(k8stesting.ListAction).GetListRestrictions().Labels.String())
+		return false, nil, nil
+	})
+	statusOptions := StatusOptions{
+		Output:           "json",
+		ReleaseName:      "app",
+		StorageType:      "configmaps",
+		TillerLabel:      "OWNER=TILLER",
+		TillerNamespace:  "kube-system",
+		TillerOutCluster: true,
+		V3Driver:         "secret",
+	}
+
+	// With all the versions deleted from Helm v2, the namespace needs to be set
+	err := Status(statusOptions, common.KubeConfig{}, &bytes.Buffer{})
+	if err == nil || !strings.Contains(err.Error(), "--target-namespace") {
+		t.Errorf("Status() without target namespace returned %v, want an error asking for --target-namespace", err)
+	}
+	if len(selectors) == 0 {
+		t.Error("Helm v2 storage was not listed")
+	}
+	for _, selector := range selectors {
+		if !strings.Contains(selector, "NAME=app") {
+			t.Errorf("Helm v2 storage listed with selector \"%s\", want it filtered by release name", selector)
+		}
+	}
+
+	statusOptions.TargetNamespace = "default"
+	var out bytes.Buffer
+	if err := Status(statusOptions, common.KubeConfig{}, &out); err != nil {
+		t.Fatalf("Status() failed: %s", err)
+	}
+	var releaseStatus ReleaseStatus
+	if err := json.Unmarshal(out.Bytes(), &releaseStatus); err != nil {
+		t.Fatal(err)
+	}
+	// No version is left in Helm v2 to tell the deleted versions from Helm v3 upgrades
+	want := []VersionStatus{
+		{Version: 1, InV3: true, V3Status: "superseded"},
+		{Version: 2, InV3: true, V3Status: "deployed"},
+	}
+	if len(releaseStatus.Versions) != len(want) {
+		t.Fatalf("Status() returned versions %+v, want %+v", releaseStatus.Versions, want)
+	}
+	for i, versionStatus := range releaseStatus.Versions {
+		if versionStatus != want[i] {
+			t.Errorf("version %d is %+v, want %+v", i, versionStatus, want[i])
+		}
+	}
+}
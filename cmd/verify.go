@@ -0,0 +1,202 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+	"helm.sh/helm/v3/pkg/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	v2 "github.com/helm/helm-2to3/pkg/v2"
+	v3 "github.com/helm/helm-2to3/pkg/v3"
+)
+
+type VerifyOptions struct {
+	APIMappings              []v3.APIMapping
+	ReleaseName              string
+	Renames                  map[string]string
+	RewriteManifestNamespace bool
+	StorageType              string
+	TargetNamespace          string
+	TillerLabel              string
+	TillerNamespace          string
+	TillerOutCluster         bool
+	V3Driver                 string
+	V3SQLConnection          string
+}
+
+func newVerifyCmd(out io.Writer) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify [flags] RELEASE",
+		Short: "verify that a converted Helm v3 release matches its Helm v2 release",
+		Args: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 1 {
+				return errors.New("name of release to be verified has to be defined")
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return runVerify(out, args)
+		},
+	}
+
+	flags := cmd.Flags()
+	settings.AddFlags(flags)
+
+	addMappingFlags(flags)
+	flags.BoolVar(&rewriteManifestNamespace, "rewrite-manifest-namespace", false, "set if the release was converted with --rewrite-manifest-namespace")
+	flags.StringVar(&targetNamespace, "target-namespace", "", "set if the release was converted with --target-namespace")
+	addV3DriverFlags(flags)
+
+	return cmd
+}
+
+func runVerify(out io.Writer, args []string) error {
+	if err := v2.ValidateStorageType(settings.ReleaseStorage); err != nil {
+		return err
+	}
+	releaseRenames, err := getRenames()
+	if err != nil {
+		return err
+	}
+	apiMappings, err := getAPIMappings()
+	if err != nil {
+		return err
+	}
+	verifyOptions := VerifyOptions{
+		APIMappings:              apiMappings,
+		ReleaseName:              args[0],
+		Renames:                  releaseRenames,
+		RewriteManifestNamespace: rewriteManifestNamespace,
+		StorageType:              settings.ReleaseStorage,
+		TargetNamespace:          targetNamespace,
+		TillerLabel:              settings.Label,
+		TillerNamespace:          settings.TillerNamespace,
+		TillerOutCluster:         settings.TillerOutCluster,
+		V3Driver:                 v3Driver,
+		V3SQLConnection:          v3SQLConnection,
+	}
+	kubeConfig := common.KubeConfig{
+		Context: settings.KubeContext,
+		File:    settings.KubeConfigFile,
+	}
+
+	return Verify(verifyOptions, kubeConfig, out)
+}
+
+// Verify compares each version of a converted Helm v3 release with the Helm v2 release version
+// it was converted from. The Helm v2 release version is mapped with the same rules as convert and
+// the manifest, values, chart metadata, hooks, status and timestamps are compared. The differences
+// are printed per field and an error is returned if any version does not match.
+func Verify(verifyOptions VerifyOptions, kubeConfig common.KubeConfig, out io.Writer) error {
+	retrieveOptions := v2.RetrieveOptions{
+		ReleaseName:      verifyOptions.ReleaseName,
+		TillerNamespace:  verifyOptions.TillerNamespace,
+		TillerLabel:      verifyOptions.TillerLabel,
+		TillerOutCluster: verifyOptions.TillerOutCluster,
+		StorageType:      verifyOptions.StorageType,
+	}
+	if err := setV3StorageDriver(verifyOptions.V3Driver, verifyOptions.V3SQLConnection, retrieveOptions, kubeConfig); err != nil {
+		return err
+	}
+	v2Releases, err := v2.GetReleaseVersions(retrieveOptions, kubeConfig)
+	if err != nil {
+		return err
+	}
+	// Converted with a single deployed version, as convert does
+	v2Releases, err = v2.SelectReleaseVersions(v2Releases, v2.HistoryOptions{})
+	if err != nil {
+		return err
+	}
+
+	// The mapping options used to convert the release
+	convertOptions := ConvertOptions{
+		APIMappings:              verifyOptions.APIMappings,
+		ReleaseName:              verifyOptions.ReleaseName,
+		Renames:                  verifyOptions.Renames,
+		RewriteManifestNamespace: verifyOptions.RewriteManifestNamespace,
+		TargetNamespace:          verifyOptions.TargetNamespace,
+	}
+	v3ReleaseName := getV3ReleaseName(convertOptions)
+
+	namespace := v2Releases[len(v2Releases)-1].Namespace
+	if verifyOptions.TargetNamespace != "" {
+		namespace = verifyOptions.TargetNamespace
+	}
+	v3Releases, err := v3.GetReleaseHistory(v3ReleaseName, namespace, kubeConfig)
+	if err != nil {
+		return err
+	}
+	if len(v3Releases) <= 0 {
+		return fmt.Errorf("[Helm 3] Release \"%s\" does not exist in namespace \"%s\"", v3ReleaseName, namespace)
+	}
+	v3ReleasesByVersion := map[int32]*release.Release{}
+	for _, v3Release := range v3Releases {
+		v3ReleasesByVersion[int32(v3Release.Version)] = v3Release
+	}
+
+	compared := 0
+	mismatches := 0
+	for _, v2Release := range v2Releases {
+		relVerName := v2.GetReleaseVersionName(verifyOptions.ReleaseName, v2Release.Version)
+		v3Release, ok := v3ReleasesByVersion[v2Release.Version]
+		if !ok {
+			fmt.Fprintf(out, "ReleaseVersion \"%s\": not converted to Helm v3\n", relVerName)
+			continue
+		}
+		delete(v3ReleasesByVersion, v2Release.Version)
+
+		expected, _, err := mapV3ReleaseVersion(v2Release, convertOptions)
+		if err != nil {
+			return err
+		}
+		diffs, err := v3.CompareReleases(expected, v3Release)
+		if err != nil {
+			return err
+		}
+		compared++
+		if len(diffs) <= 0 {
+			fmt.Fprintf(out, "ReleaseVersion \"%s\": matches\n", relVerName)
+			continue
+		}
+		mismatches++
+		fmt.Fprintf(out, "ReleaseVersion \"%s\": does not match\n", relVerName)
+		for _, diff := range diffs {
+			fmt.Fprintf(out, "  %s:\n", diff.Field)
+			for _, line := range diff.Lines {
+				fmt.Fprintf(out, "    %s\n", line)
+			}
+		}
+	}
+	for _, v3Release := range v3Releases {
+		if _, ok := v3ReleasesByVersion[int32(v3Release.Version)]; ok {
+			fmt.Fprintf(out, "ReleaseVersion \"%s\": not in Helm v2\n", v2.GetReleaseVersionName(v3ReleaseName, int32(v3Release.Version)))
+		}
+	}
+
+	if compared <= 0 {
+		return fmt.Errorf("Release \"%s\" has no versions in both Helm v2 and Helm v3 to verify", verifyOptions.ReleaseName)
+	}
+	if mismatches > 0 {
+		return fmt.Errorf("%d of %d versions of release \"%s\" do not match between Helm v2 and Helm v3", mismatches, compared, verifyOptions.ReleaseName)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ManifestFileName is the name of the manifest file in a backup archive
+	ManifestFileName = "manifest.yaml"
+	objectsDir       = "objects/"
+)
+
+// Manifest describes the content of a backup archive
+type Manifest struct {
+	CaptureTime     time.Time `json:"captureTime"`
+	TillerNamespace string    `json:"tillerNamespace"`
+	StorageType     string    `json:"storageType"`
+	Objects         []Object  `json:"objects"`
+}
+
+// Object is a Helm v2 storage object in a backup archive
+type Object struct {
+	// Name is the name of the object, which is the release version name
+	Name string `json:"name"`
+	// Kind is the kind of the object, 'ConfigMap' or 'Secret'. It is empty in older archives.
+	Kind     string `json:"kind,omitempty"`
+	File     string `json:"file"`
+	Checksum string `json:"sha256"`
+}
+
+// Write writes a backup archive of Helm v2 storage objects. The archive is a gzipped tar with
+// the manifest and a YAML file per object. The objects and their checksums are added to the
+// manifest. An object passed more than once, by kind and name, is written once.
+func Write(w io.Writer, manifest Manifest, objects []runtime.Object) error {
+	files := map[string][]byte{}
+	manifest.Objects = []Object{}
+	for _, object := range objects {
+		name, exported, err := exportObject(object)
+		if err != nil {
+			return err
+		}
+		data, err := yaml.Marshal(exported)
+		if err != nil {
+			return err
+		}
+		kind := exported.GetObjectKind().GroupVersionKind().Kind
+		fileName := objectsDir + strings.ToLower(kind) + "/" + name + ".yaml"
+		if existing, ok := files[fileName]; ok {
+			if string(existing) != string(data) {
+				return fmt.Errorf("%s \"%s\" is passed twice with different content", kind, name)
+			}
+			continue
+		}
+		files[fileName] = data
+		checksum := sha256.Sum256(data)
+		manifest.Objects = append(manifest.Objects, Object{
+			Name:     name,
+			Kind:     kind,
+			File:     fileName,
+			Checksum: hex.EncodeToString(checksum[:]),
+		})
+	}
+	manifestData, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	if err := writeFile(tarWriter, ManifestFileName, manifestData, manifest.CaptureTime); err != nil {
+		return err
+	}
+	for _, object := range manifest.Objects {
+		if err := writeFile(tarWriter, object.File, files[object.File], manifest.CaptureTime); err != nil {
+			return err
+		}
+	}
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// Read reads a backup archive. The checksum of each object in the manifest is validated and an
+// error is returned if an object is missing, does not match its checksum or is not in the manifest.
+// An object listed more than once in the manifest is returned once.
+func Read(r io.Reader) (*Manifest, []runtime.Object, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer gzipReader.Close()
+	tarReader := tar.NewReader(gzipReader)
+
+	files := map[string][]byte{}
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := ioutil.ReadAll(tarReader)
+		if err != nil {
+			return nil, nil, err
+		}
+		files[header.Name] = data
+	}
+
+	manifestData, ok := files[ManifestFileName]
+	if !ok {
+		return nil, nil, fmt.Errorf("archive has no %s file", ManifestFileName)
+	}
+	manifest := new(Manifest)
+	if err := yaml.Unmarshal(manifestData, manifest); err != nil {
+		return nil, nil, fmt.Errorf("Failed to parse %s due to the following error: %s", ManifestFileName, err)
+	}
+	delete(files, ManifestFileName)
+
+	objects := []runtime.Object{}
+	// Checksums of the files read, as a file can be listed more than once
+	checksums := map[string]string{}
+	for _, object := range manifest.Objects {
+		if checksum, ok := checksums[object.File]; ok {
+			if checksum != object.Checksum {
+				return nil, nil, fmt.Errorf("object \"%s\" is listed twice with different checksums", object.Name)
+			}
+			continue
+		}
+		data, ok := files[object.File]
+		if !ok {
+			return nil, nil, fmt.Errorf("object \"%s\" is missing from the archive", object.Name)
+		}
+		delete(files, object.File)
+		checksum := sha256.Sum256(data)
+		if hex.EncodeToString(checksum[:]) != object.Checksum {
+			return nil, nil, fmt.Errorf("object \"%s\" does not match its checksum", object.Name)
+		}
+		checksums[object.File] = object.Checksum
+		imported, err := importObject(data)
+		if err != nil {
+			return nil, nil, fmt.Errorf("Failed to parse object \"%s\" due to the following error: %s", object.Name, err)
+		}
+		objects = append(objects, imported)
+	}
+	for fileName := range files {
+		return nil, nil, fmt.Errorf("file \"%s\" of the archive is not in the manifest", fileName)
+	}
+
+	return manifest, objects, nil
+}
+
+func writeFile(tarWriter *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0600,
+		Size:    int64(len(data)),
+		ModTime: modTime,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tarWriter.Write(data)
+	return err
+}
+
+// exportObject returns a copy of a storage object without the metadata set by the cluster,
+// so that it can be created again
+func exportObject(object runtime.Object) (string, runtime.Object, error) {
+	switch item := object.(type) {
+	case *corev1.ConfigMap:
+		return item.Name, &corev1.ConfigMap{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+			ObjectMeta: exportObjectMeta(item.ObjectMeta),
+			Data:       item.Data,
+			BinaryData: item.BinaryData,
+		}, nil
+	case *corev1.Secret:
+		return item.Name, &corev1.Secret{
+			TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: exportObjectMeta(item.ObjectMeta),
+			Type:       item.Type,
+			Data:       item.Data,
+		}, nil
+	}
+	return "", nil, fmt.Errorf("storage object of type %T cannot be backed up", object)
+}
+
+func importObject(data []byte) (runtime.Object, error) {
+	var typeMeta metav1.TypeMeta
+	if err := yaml.Unmarshal(data, &typeMeta); err != nil {
+		return nil, err
+	}
+	var object runtime.Object
+	switch typeMeta.Kind {
+	case "ConfigMap":
+		object = new(corev1.ConfigMap)
+	case "Secret":
+		object = new(corev1.Secret)
+	default:
+		return nil, fmt.Errorf("kind \"%s\" is not a Helm v2 storage object", typeMeta.Kind)
+	}
+	if err := yaml.Unmarshal(data, object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+func exportObjectMeta(meta metav1.ObjectMeta) metav1.ObjectMeta {
+	return metav1.ObjectMeta{
+		Name:        meta.Name,
+		Namespace:   meta.Namespace,
+		Labels:      meta.Labels,
+		Annotations: meta.Annotations,
+	}
+}
@@ -0,0 +1,117 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+func newConfigMap(name, release string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "ConfigMap"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "kube-system",
+			Labels:    map[string]string{"OWNER": "TILLER", "NAME": "a"},
+		},
+		Data: map[string]string{"release": release},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	configMap := newConfigMap("a.v1", "data")
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "a.v1", Namespace: "kube-system"},
+		Data:       map[string][]byte{"release": []byte("data")},
+	}
+	manifest := Manifest{TillerNamespace: "kube-system", StorageType: "both", CaptureTime: time.Unix(0, 0).UTC()}
+
+	var archive bytes.Buffer
+	// The ConfigMap is passed twice, as by overlapping release selections
+	if err := Write(&archive, manifest, []runtime.Object{configMap, configMap.DeepCopy(), secret}); err != nil {
+		t.Fatalf("Write() failed: %s", err)
+	}
+	readManifest, objects, err := Read(&archive)
+	if err != nil {
+		t.Fatalf("Read() failed: %s", err)
+	}
+	if len(readManifest.Objects) != 2 || len(objects) != 2 {
+		t.Fatalf("Read() returned %d manifest entries and %d objects, want 2 of each", len(readManifest.Objects), len(objects))
+	}
+	kinds := map[string]bool{}
+	for _, object := range readManifest.Objects {
+		kinds[object.Kind] = object.Name == "a.v1"
+	}
+	if !kinds["ConfigMap"] || !kinds["Secret"] {
+		t.Errorf("Read() returned manifest entries %v, want the ConfigMap and Secret \"a.v1\"", readManifest.Objects)
+	}
+}
+
+func TestWriteConflict(t *testing.T) {
+	var archive bytes.Buffer
+	err := Write(&archive, Manifest{}, []runtime.Object{newConfigMap("a.v1", "data"), newConfigMap("a.v1", "other")})
+	if err == nil {
+		t.Fatal("Write() of two different objects of the same name succeeded, want error")
+	}
+}
+
+func TestReadRepeatedEntries(t *testing.T) {
+	// Archives written before objects were deduplicated list an object once per selection
+	data, err := yaml.Marshal(newConfigMap("a.v1", "data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checksum := sha256.Sum256(data)
+	object := Object{Name: "a.v1", File: "objects/a.v1.yaml", Checksum: hex.EncodeToString(checksum[:])}
+	manifestData, err := yaml.Marshal(Manifest{Objects: []Object{object, object}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{{ManifestFileName, manifestData}, {object.File, data}, {object.File, data}} {
+		if err := writeFile(tarWriter, file.name, file.data, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tarWriter.Close()
+	gzipWriter.Close()
+
+	_, objects, err := Read(&archive)
+	if err != nil {
+		t.Fatalf("Read() failed: %s", err)
+	}
+	if len(objects) != 1 {
+		t.Errorf("Read() returned %d objects, want 1", len(objects))
+	}
+}
@@ -0,0 +1,175 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+)
+
+// Steps of a release version conversion which are recorded in the journal
+const (
+	StepCreateV3   = "create-v3"
+	StepRollbackV3 = "rollback-v3"
+	StepDeleteV2   = "delete-v2"
+)
+
+// Entry is a step of a release version conversion which has completed
+type Entry struct {
+	Release   string    `json:"release"`
+	Version   int32     `json:"version"`
+	Step      string    `json:"step"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// Journal is a local file which records the completed steps of a migration,
+// one JSON entry per line, so that an interrupted migration can be resumed
+type Journal struct {
+	fileName string
+	file     *os.File
+	done     map[string]bool
+	// end is the length of the complete entries of the file, which is shorter than the file
+	// when the process was killed while an entry was written
+	end int64
+	// missingNewline is true if the last complete entry is not ended by a new line
+	missingNewline bool
+}
+
+// Open loads the entries of the journal file if it exists. Unless the journal is
+// read only, the file is created if needed and new entries are appended to it.
+func Open(fileName string, readOnly bool) (*Journal, error) {
+	journal := &Journal{
+		fileName: fileName,
+		done:     map[string]bool{},
+	}
+
+	if err := journal.load(); err != nil {
+		return nil, err
+	}
+	if readOnly {
+		return journal, nil
+	}
+
+	file, err := os.OpenFile(fileName, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open checkpoint file \"%s\" due to the following error: %s", fileName, err)
+	}
+	journal.file = file
+	// New entries are appended after the last complete entry, so that they are not
+	// appended to an entry which was not written in full
+	err = file.Truncate(journal.end)
+	if err == nil && journal.missingNewline {
+		_, err = file.Write([]byte{'\n'})
+	}
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Failed to write to checkpoint file \"%s\" due to the following error: %s", fileName, err)
+	}
+	return journal, nil
+}
+
+// IsDone returns true if the step has completed for the release version
+func (j *Journal) IsDone(release string, version int32, step string) bool {
+	if j == nil {
+		return false
+	}
+	return j.done[key(release, version, step)]
+}
+
+// Record appends a completed step for the release version to the journal file
+func (j *Journal) Record(release string, version int32, step string) error {
+	if j == nil {
+		return nil
+	}
+	j.apply(Entry{Release: release, Version: version, Step: step})
+	if j.file == nil {
+		return nil
+	}
+
+	entry := Entry{
+		Release:   release,
+		Version:   version,
+		Step:      step,
+		Timestamp: time.Now().UTC(),
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("Failed to write to checkpoint file \"%s\" due to the following error: %s", j.fileName, err)
+	}
+	// Sync so that the entry survives the process being killed
+	return j.file.Sync()
+}
+
+// Close closes the journal file
+func (j *Journal) Close() error {
+	if j == nil || j.file == nil {
+		return nil
+	}
+	return j.file.Close()
+}
+
+func (j *Journal) load() error {
+	data, err := ioutil.ReadFile(j.fileName)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to read checkpoint file \"%s\" due to the following error: %s", j.fileName, err)
+	}
+
+	lines := bytes.Split(data, []byte{'\n'})
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(line, &entry); err != nil {
+			if i == len(lines)-1 {
+				// The last entry was not written in full, so its step is not known to have
+				// completed and it is cut off before new entries are appended
+				j.end = int64(len(data) - len(line))
+				return nil
+			}
+			return fmt.Errorf("Failed to parse line %d of checkpoint file \"%s\" due to the following error: %s", i+1, j.fileName, err)
+		}
+		j.apply(entry)
+	}
+	j.end = int64(len(data))
+	j.missingNewline = len(data) > 0 && data[len(data)-1] != '\n'
+	return nil
+}
+
+func (j *Journal) apply(entry Entry) {
+	switch entry.Step {
+	case StepRollbackV3:
+		// A rolled back version has to be created again
+		delete(j.done, key(entry.Release, entry.Version, StepCreateV3))
+	default:
+		j.done[key(entry.Release, entry.Version, entry.Step)] = true
+	}
+}
+
+func key(release string, version int32, step string) string {
+	return fmt.Sprintf("%s.v%d/%s", release, version, step)
+}
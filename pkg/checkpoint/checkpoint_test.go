@@ -0,0 +1,132 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package checkpoint
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func openJournal(t *testing.T, fileName string, readOnly bool) *Journal {
+	journal, err := Open(fileName, readOnly)
+	if err != nil {
+		t.Fatalf("Open() failed: %s", err)
+	}
+	t.Cleanup(func() {
+		journal.Close()
+	})
+	return journal
+}
+
+func TestRecordAndReopen(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	journal := openJournal(t, fileName, false)
+	for _, entry := range []Entry{
+		{Release: "app", Version: 1, Step: StepCreateV3},
+		{Release: "app", Version: 2, Step: StepCreateV3},
+		{Release: "app", Version: 2, Step: StepRollbackV3},
+		{Release: "app", Version: 1, Step: StepDeleteV2},
+	} {
+		if err := journal.Record(entry.Release, entry.Version, entry.Step); err != nil {
+			t.Fatalf("Record() failed: %s", err)
+		}
+	}
+	journal.Close()
+
+	reopened := openJournal(t, fileName, false)
+	tests := []struct {
+		version int32
+		step    string
+		want    bool
+	}{
+		{version: 1, step: StepCreateV3, want: true},
+		{version: 1, step: StepDeleteV2, want: true},
+		{version: 2, step: StepCreateV3, want: false},
+		{version: 2, step: StepDeleteV2, want: false},
+		{version: 3, step: StepCreateV3, want: false},
+	}
+	for _, tt := range tests {
+		if got := reopened.IsDone("app", tt.version, tt.step); got != tt.want {
+			t.Errorf("IsDone(\"app\", %d, %q) = %t, want %t", tt.version, tt.step, got, tt.want)
+		}
+	}
+	if reopened.IsDone("other", 1, StepCreateV3) {
+		t.Error("IsDone() is true for a release which was not recorded")
+	}
+}
+
+func TestOpenTruncated(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	data := `{"release":"app","version":1,"step":"create-v3"}` + "\n" + `{"release":"app","vers`
+	if err := ioutil.WriteFile(fileName, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	journal := openJournal(t, fileName, false)
+	if !journal.IsDone("app", 1, StepCreateV3) {
+		t.Error("the entry before the truncated line is not loaded")
+	}
+	if err := journal.Record("app", 2, StepCreateV3); err != nil {
+		t.Fatalf("Record() failed: %s", err)
+	}
+	journal.Close()
+
+	reopened := openJournal(t, fileName, true)
+	if !reopened.IsDone("app", 2, StepCreateV3) {
+		t.Error("the entry recorded after the truncated line is not loaded")
+	}
+}
+
+func TestOpenCorrupted(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	data := "not json\n" + `{"release":"app","version":1,"step":"create-v3"}` + "\n"
+	if err := ioutil.WriteFile(fileName, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(fileName, true); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("Open() error = %v, want an error for line 1", err)
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	fileName := filepath.Join(t.TempDir(), "checkpoint.jsonl")
+	journal := openJournal(t, fileName, true)
+	if err := journal.Record("app", 1, StepCreateV3); err != nil {
+		t.Fatalf("Record() failed: %s", err)
+	}
+	if !journal.IsDone("app", 1, StepCreateV3) {
+		t.Error("IsDone() is false for a step recorded in a read only journal")
+	}
+	if _, err := ioutil.ReadFile(fileName); err == nil {
+		t.Error("a read only journal created the checkpoint file")
+	}
+}
+
+func TestNilJournal(t *testing.T) {
+	var journal *Journal
+	if journal.IsDone("app", 1, StepCreateV3) {
+		t.Error("IsDone() is true for a nil journal")
+	}
+	if err := journal.Record("app", 1, StepCreateV3); err != nil {
+		t.Errorf("Record() failed for a nil journal: %s", err)
+	}
+	if err := journal.Close(); err != nil {
+		t.Errorf("Close() failed for a nil journal: %s", err)
+	}
+}
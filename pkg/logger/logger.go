@@ -0,0 +1,240 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log entry
+type Level int
+
+// Log levels
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var levelNames = map[Level]string{
+	LevelDebug: "debug",
+	LevelInfo:  "info",
+	LevelWarn:  "warn",
+	LevelError: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// Fields are the structured fields of a log entry, e.g. the release, version, namespace and operation
+type Fields map[string]interface{}
+
+// Field names used across commands
+const (
+	FieldNamespace = "namespace"
+	FieldOperation = "operation"
+	FieldRelease   = "release"
+	FieldVersion   = "version"
+)
+
+// Logger writes log entries at or above a level, as text or as JSON. The text format is the format
+// of the standard log package and does not include the fields. The JSON format writes an object per
+// entry with the time, level, message and fields.
+type Logger struct {
+	out    io.Writer
+	level  Level
+	format string
+	fields Fields
+	mu     *sync.Mutex
+}
+
+var defaultLogger = New(os.Stderr, LevelInfo, FormatText)
+
+// New returns a logger
+func New(out io.Writer, level Level, format string) *Logger {
+	return &Logger{
+		out:    out,
+		level:  level,
+		format: format,
+		fields: Fields{},
+		mu:     &sync.Mutex{},
+	}
+}
+
+// ParseLevel returns the level of a name, 'debug', 'info', 'warn' or 'error'
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return LevelInfo, fmt.Errorf("log level \"%s\" is not supported, it needs to be 'debug', 'info', 'warn' or 'error'", name)
+}
+
+// ValidateFormat returns an error if the format is not supported
+func ValidateFormat(format string) error {
+	if format != FormatText && format != FormatJSON {
+		return fmt.Errorf("log format \"%s\" is not supported, it needs to be '%s' or '%s'", format, FormatText, FormatJSON)
+	}
+	return nil
+}
+
+// SetDefault sets the logger used by the package level functions
+func SetDefault(l *Logger) {
+	defaultLogger = l
+}
+
+// Default returns the logger used by the package level functions
+func Default() *Logger {
+	return defaultLogger
+}
+
+// IsDebug returns whether debug entries are written
+func (l *Logger) IsDebug() bool {
+	return l.level <= LevelDebug
+}
+
+// With returns a logger which adds fields to the entries
+func (l *Logger) With(fields Fields) *Logger {
+	merged := Fields{}
+	for key, value := range l.fields {
+		merged[key] = value
+	}
+	for key, value := range fields {
+		merged[key] = value
+	}
+	return &Logger{
+		out:    l.out,
+		level:  l.level,
+		format: l.format,
+		fields: merged,
+		mu:     l.mu,
+	}
+}
+
+// Debugf writes a debug entry
+func (l *Logger) Debugf(format string, v ...interface{}) {
+	l.write(LevelDebug, format, v...)
+}
+
+// Infof writes an info entry
+func (l *Logger) Infof(format string, v ...interface{}) {
+	l.write(LevelInfo, format, v...)
+}
+
+// Warnf writes a warning entry
+func (l *Logger) Warnf(format string, v ...interface{}) {
+	l.write(LevelWarn, format, v...)
+}
+
+// Errorf writes an error entry
+func (l *Logger) Errorf(format string, v ...interface{}) {
+	l.write(LevelError, format, v...)
+}
+
+func (l *Logger) write(level Level, format string, v ...interface{}) {
+	if level < l.level {
+		return
+	}
+	message := format
+	if len(v) > 0 {
+		message = fmt.Sprintf(format, v...)
+	}
+	now := time.Now()
+
+	var line string
+	switch l.format {
+	case FormatJSON:
+		message = strings.TrimSpace(message)
+		// Blank lines only space out the text output
+		if message == "" {
+			return
+		}
+		entry := map[string]interface{}{}
+		for key, value := range l.fields {
+			entry[key] = value
+		}
+		entry["time"] = now.Format(time.RFC3339)
+		entry["level"] = level.String()
+		entry["msg"] = message
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return
+		}
+		line = string(data) + "\n"
+	default:
+		line = now.Format("2006/01/02 15:04:05") + " " + strings.TrimSuffix(message, "\n") + "\n"
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.out, line)
+}
+
+// With returns a logger, based on the default logger, which adds fields to the entries
+func With(fields Fields) *Logger {
+	return defaultLogger.With(fields)
+}
+
+// Debugf writes a debug entry with the default logger
+func Debugf(format string, v ...interface{}) {
+	defaultLogger.Debugf(format, v...)
+}
+
+// Infof writes an info entry with the default logger
+func Infof(format string, v ...interface{}) {
+	defaultLogger.Infof(format, v...)
+}
+
+// Warnf writes a warning entry with the default logger
+func Warnf(format string, v ...interface{}) {
+	defaultLogger.Warnf(format, v...)
+}
+
+// Errorf writes an error entry with the default logger
+func Errorf(format string, v ...interface{}) {
+	defaultLogger.Errorf(format, v...)
+}
+
+// Writer returns a writer which writes each write as an info entry, so that the output of the
+// standard log package can be redirected to the logger
+func (l *Logger) Writer() io.Writer {
+	return entryWriter{logger: l}
+}
+
+type entryWriter struct {
+	logger *Logger
+}
+
+func (w entryWriter) Write(p []byte) (int, error) {
+	w.logger.Infof("%s", string(p))
+	return len(p), nil
+}
@@ -0,0 +1,186 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// writeAll writes an entry at each level
+func writeAll(l *Logger) {
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 2)
+	l.Warnf("warn %d", 3)
+	l.Errorf("error %d", 4)
+}
+
+// decodeEntries decodes the JSON entries of the output, one per line
+func decodeEntries(t *testing.T, output string) []map[string]interface{} {
+	t.Helper()
+	entries := []map[string]interface{}{}
+	for _, line := range strings.Split(strings.TrimSuffix(output, "\n"), "\n") {
+		if line == "" {
+			continue
+		}
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %q is not a JSON object: %s", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestLevelFiltering(t *testing.T) {
+	tests := []struct {
+		level   Level
+		want    []string
+		isDebug bool
+	}{
+		{level: LevelDebug, want: []string{"debug 1", "info 2", "warn 3", "error 4"}, isDebug: true},
+		{level: LevelInfo, want: []string{"info 2", "warn 3", "error 4"}},
+		{level: LevelWarn, want: []string{"warn 3", "error 4"}},
+		{level: LevelError, want: []string{"error 4"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var out bytes.Buffer
+			l := New(&out, tt.level, FormatJSON)
+			writeAll(l)
+			messages := []string{}
+			for _, entry := range decodeEntries(t, out.String()) {
+				messages = append(messages, entry["msg"].(string))
+			}
+			if !reflect.DeepEqual(messages, tt.want) {
+				t.Errorf("messages are %v, want %v", messages, tt.want)
+			}
+			if l.IsDebug() != tt.isDebug {
+				t.Errorf("IsDebug() = %t, want %t", l.IsDebug(), tt.isDebug)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, name := range []string{"debug", "info", "warn", "error", "WARN"} {
+		level, err := ParseLevel(name)
+		if err != nil {
+			t.Errorf("ParseLevel(%q) failed: %s", name, err)
+		} else if level.String() != strings.ToLower(name) {
+			t.Errorf("ParseLevel(%q) = %s", name, level)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Error("ParseLevel(\"trace\") did not fail")
+	}
+	if err := ValidateFormat("yaml"); err == nil {
+		t.Error("ValidateFormat(\"yaml\") did not fail")
+	}
+}
+
+func TestTextFormat(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out, LevelInfo, FormatText).With(Fields{FieldRelease: "app"})
+	l.Infof("[Helm 2] ReleaseVersion \"%s\" will be converted.\n", "app.v1")
+	l.Infof("")
+
+	// The text format is the format of the standard log package, without the fields
+	pattern := regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \[Helm 2\] ReleaseVersion "app.v1" will be converted\.\n\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \n$`)
+	if !pattern.MatchString(out.String()) {
+		t.Errorf("text output is %q", out.String())
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out, LevelInfo, FormatJSON)
+	l.Warnf("  release \"%s\" is skipped\n", "app")
+	// Blank lines are not written
+	l.Infof("\n")
+
+	entries := decodeEntries(t, out.String())
+	if len(entries) != 1 {
+		t.Fatalf("%d entries are written, want 1: %q", len(entries), out.String())
+	}
+	entry := entries[0]
+	if entry["level"] != "warn" || entry["msg"] != "release \"app\" is skipped" {
+		t.Errorf("entry is %v", entry)
+	}
+	if _, ok := entry["time"].(string); !ok {
+		t.Errorf("entry has no time: %v", entry)
+	}
+}
+
+func TestWith(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out, LevelInfo, FormatJSON)
+	releaseLog := l.With(Fields{FieldOperation: "convert", FieldRelease: "app"})
+	versionLog := releaseLog.With(Fields{FieldVersion: 2, FieldRelease: "web"})
+	versionLog.Infof("version")
+	releaseLog.Infof("release")
+	l.Infof("plain")
+
+	entries := decodeEntries(t, out.String())
+	if len(entries) != 3 {
+		t.Fatalf("%d entries are written, want 3: %q", len(entries), out.String())
+	}
+	want := []map[string]interface{}{
+		{FieldOperation: "convert", FieldRelease: "web", FieldVersion: float64(2)},
+		{FieldOperation: "convert", FieldRelease: "app"},
+		{},
+	}
+	for i, entry := range entries {
+		fields := map[string]interface{}{}
+		for key, value := range entry {
+			if key != "time" && key != "level" && key != "msg" {
+				fields[key] = value
+			}
+		}
+		if !reflect.DeepEqual(fields, want[i]) {
+			t.Errorf("fields of entry %q are %v, want %v", entry["msg"], fields, want[i])
+		}
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var out bytes.Buffer
+	l := New(&out, LevelInfo, FormatJSON).With(Fields{FieldOperation: "cleanup"})
+	std := log.New(l.Writer(), "", 0)
+	std.Printf("100%% of %s done", "app")
+
+	entries := decodeEntries(t, out.String())
+	if len(entries) != 1 {
+		t.Fatalf("%d entries are written, want 1: %q", len(entries), out.String())
+	}
+	// The message is written as is, not used as a format
+	if entries[0]["level"] != "info" || entries[0]["msg"] != "100% of app done" || entries[0][FieldOperation] != "cleanup" {
+		t.Errorf("entry is %v", entries[0])
+	}
+
+	// Writes are filtered by the level of the logger
+	out.Reset()
+	log.New(New(&out, LevelWarn, FormatText).Writer(), "", 0).Print("dropped")
+	if out.Len() != 0 {
+		t.Errorf("info entry written at warn level: %q", out.String())
+	}
+}
@@ -0,0 +1,154 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"sigs.k8s.io/yaml"
+)
+
+// Output formats of a plan
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Plan lists the actions a command takes, so that they can be reviewed before the command is run.
+// The methods of a nil plan do nothing, so that a plan only needs to be built when requested.
+type Plan struct {
+	Command string `json:"command"`
+	// ReleaseVersions are the Helm v3 release versions to create
+	ReleaseVersions []ReleaseVersion `json:"createReleaseVersions"`
+	// StorageObjects are the Helm v2 storage objects to delete
+	StorageObjects []Object `json:"deleteStorageObjects"`
+	// TillerObjects are the Tiller objects to remove
+	TillerObjects []Object `json:"removeTillerObjects"`
+	// HomeFolders are the Helm v2 home folder paths to delete
+	HomeFolders []string `json:"deleteHomeFolders"`
+	// FailedReleases are the Helm v2 releases which failed, whose actions above may be incomplete
+	FailedReleases []FailedRelease `json:"failedReleases"`
+}
+
+// ReleaseVersion is a Helm v3 release version to create from a Helm v2 release version
+type ReleaseVersion struct {
+	// V2Release is the name of the Helm v2 release
+	V2Release string `json:"v2Release"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Version   int32  `json:"version"`
+	Status    string `json:"status"`
+	Chart     string `json:"chart"`
+}
+
+// FailedRelease is a Helm v2 release which failed and the error it failed with
+type FailedRelease struct {
+	V2Release string `json:"v2Release"`
+	Error     string `json:"error"`
+}
+
+// Object is a Kubernetes object
+type Object struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+// New returns an empty plan for a command
+func New(command string) *Plan {
+	return &Plan{
+		Command:         command,
+		ReleaseVersions: []ReleaseVersion{},
+		StorageObjects:  []Object{},
+		TillerObjects:   []Object{},
+		HomeFolders:     []string{},
+		FailedReleases:  []FailedRelease{},
+	}
+}
+
+// ValidateFormat returns an error if a plan cannot be written in the format
+func ValidateFormat(format string) error {
+	if format != FormatJSON && format != FormatYAML {
+		return fmt.Errorf("output format \"%s\" is not supported, it needs to be '%s' or '%s'", format, FormatJSON, FormatYAML)
+	}
+	return nil
+}
+
+// AddReleaseVersion adds a Helm v3 release version to create
+func (p *Plan) AddReleaseVersion(releaseVersion ReleaseVersion) {
+	if p == nil {
+		return
+	}
+	p.ReleaseVersions = append(p.ReleaseVersions, releaseVersion)
+}
+
+// AddStorageObject adds a Helm v2 storage object to delete
+func (p *Plan) AddStorageObject(object Object) {
+	if p == nil {
+		return
+	}
+	p.StorageObjects = append(p.StorageObjects, object)
+}
+
+// AddTillerObject adds a Tiller object to remove
+func (p *Plan) AddTillerObject(object Object) {
+	if p == nil {
+		return
+	}
+	p.TillerObjects = append(p.TillerObjects, object)
+}
+
+// AddHomeFolder adds a Helm v2 home folder path to delete
+func (p *Plan) AddHomeFolder(path string) {
+	if p == nil {
+		return
+	}
+	p.HomeFolders = append(p.HomeFolders, path)
+}
+
+// AddFailedRelease adds a Helm v2 release which failed
+func (p *Plan) AddFailedRelease(v2Release string, err error) {
+	if p == nil {
+		return
+	}
+	p.FailedReleases = append(p.FailedReleases, FailedRelease{V2Release: v2Release, Error: err.Error()})
+}
+
+// Write writes the plan in a format, 'json' or 'yaml'
+func (p *Plan) Write(out io.Writer, format string) error {
+	if p == nil {
+		return nil
+	}
+	var data []byte
+	var err error
+	switch format {
+	case FormatJSON:
+		data, err = json.MarshalIndent(p, "", "  ")
+		data = append(data, '\n')
+	case FormatYAML:
+		data, err = yaml.Marshal(p)
+	default:
+		return ValidateFormat(format)
+	}
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(data)
+	return err
+}
@@ -0,0 +1,192 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package preflight
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+
+	authv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/restmapper"
+	"sigs.k8s.io/yaml"
+)
+
+var docSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusPass Status = "PASS"
+	StatusWarn Status = "WARN"
+	StatusFail Status = "FAIL"
+)
+
+// Result is the outcome of a check performed on a target, like a namespace or a release
+type Result struct {
+	Check   string
+	Target  string
+	Status  Status
+	Message string
+}
+
+// Permission is a verb on a resource in a namespace which needs to be allowed
+type Permission struct {
+	Namespace string
+	Group     string
+	Resource  string
+	Verb      string
+	// Severity is the status reported when the permission is denied
+	Severity Status
+}
+
+// Pass returns a passed result
+func Pass(check, target, message string) Result {
+	return Result{Check: check, Target: target, Status: StatusPass, Message: message}
+}
+
+// Warn returns a warning result
+func Warn(check, target, message string) Result {
+	return Result{Check: check, Target: target, Status: StatusWarn, Message: message}
+}
+
+// Fail returns a failed result
+func Fail(check, target, message string) Result {
+	return Result{Check: check, Target: target, Status: StatusFail, Message: message}
+}
+
+// CheckNamespace checks that a namespace exists
+func CheckNamespace(clientSet kubernetes.Interface, namespace string) Result {
+	const check = "namespace"
+	_, err := clientSet.CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		return Pass(check, namespace, "namespace exists")
+	case apierrors.IsNotFound(err):
+		return Fail(check, namespace, "namespace does not exist")
+	case apierrors.IsForbidden(err):
+		return Warn(check, namespace, "not allowed to get namespace, existence could not be checked")
+	default:
+		return Fail(check, namespace, fmt.Sprintf("failed to get namespace: %s", err))
+	}
+}
+
+// CheckPermission checks that the current user is allowed the permission using a SelfSubjectAccessReview
+func CheckPermission(clientSet kubernetes.Interface, permission Permission) Result {
+	const check = "rbac"
+	resource := permission.Resource
+	if permission.Group != "" {
+		resource = fmt.Sprintf("%s.%s", permission.Resource, permission.Group)
+	}
+	target := fmt.Sprintf("%s %s in %s", permission.Verb, resource, permission.Namespace)
+
+	review := &authv1.SelfSubjectAccessReview{
+		Spec: authv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Namespace: permission.Namespace,
+				Group:     permission.Group,
+				Resource:  permission.Resource,
+				Verb:      permission.Verb,
+			},
+		},
+	}
+	response, err := clientSet.AuthorizationV1().SelfSubjectAccessReviews().Create(context.Background(), review, metav1.CreateOptions{})
+	if err != nil {
+		return Fail(check, target, fmt.Sprintf("failed to review access: %s", err))
+	}
+	if !response.Status.Allowed {
+		return Result{Check: check, Target: target, Status: permission.Severity, Message: "not allowed"}
+	}
+	return Pass(check, target, "allowed")
+}
+
+// CheckAdoptPermissions checks that the current user is allowed to get and patch the resources of
+// a release manifest, as needed to adopt them. Resources without a namespace are in the release
+// namespace. The kinds are mapped to resources with the API discovery of the cluster.
+func CheckAdoptPermissions(clientSet kubernetes.Interface, manifest, namespace string) []Result {
+	const check = "rbac"
+	groupResources, err := restmapper.GetAPIGroupResources(clientSet.Discovery())
+	if err != nil {
+		return []Result{Fail(check, namespace, fmt.Sprintf("failed to discover the API resources: %s", err))}
+	}
+	mapper := restmapper.NewDiscoveryRESTMapper(groupResources)
+
+	results := []Result{}
+	permissions := map[string]Permission{}
+	for _, doc := range docSeparator.Split(manifest, -1) {
+		var object struct {
+			APIVersion string `json:"apiVersion"`
+			Kind       string `json:"kind"`
+			Metadata   struct {
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+		}
+		if err := yaml.Unmarshal([]byte(doc), &object); err != nil || object.Kind == "" {
+			continue
+		}
+		gvk := schema.FromAPIVersionAndKind(object.APIVersion, object.Kind)
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			results = append(results, Warn(check, fmt.Sprintf("%s in %s", object.Kind, namespace), "kind is not served by the cluster, permissions could not be checked"))
+			continue
+		}
+		resourceNamespace := ""
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			resourceNamespace = object.Metadata.Namespace
+			if resourceNamespace == "" {
+				resourceNamespace = namespace
+			}
+		}
+		for _, verb := range []string{"get", "patch"} {
+			permission := Permission{
+				Namespace: resourceNamespace,
+				Group:     mapping.Resource.Group,
+				Resource:  mapping.Resource.Resource,
+				Verb:      verb,
+				Severity:  StatusFail,
+			}
+			permissions[fmt.Sprintf("%s/%s/%s/%s", permission.Namespace, permission.Group, permission.Resource, verb)] = permission
+		}
+	}
+
+	keys := []string{}
+	for key := range permissions {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		results = append(results, CheckPermission(clientSet, permissions[key]))
+	}
+	return results
+}
+
+// HasFailures returns true if any of the results failed
+func HasFailures(results []Result) bool {
+	for _, result := range results {
+		if result.Status == StatusFail {
+			return true
+		}
+	}
+	return false
+}
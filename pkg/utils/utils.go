@@ -20,13 +20,13 @@ import (
 	"bufio"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/pkg/errors"
 
+	"github.com/helm/helm-2to3/pkg/logger"
 	v2 "github.com/helm/helm-2to3/pkg/v2"
 	v3 "github.com/helm/helm-2to3/pkg/v3"
 )
@@ -35,57 +35,57 @@ import (
 // Note that this is not a direct 1-1 copy
 func Copyv2HomeTov3(dryRun bool) error {
 	v2HomeDir := v2.HomeDir()
-	log.Printf("[Helm 2] Home directory: %s\n", v2HomeDir)
+	logger.Infof("[Helm 2] Home directory: %s\n", v2HomeDir)
 	v3ConfigDir := v3.ConfigDir()
-	log.Printf("[Helm 3] Config directory: %s\n", v3ConfigDir)
+	logger.Infof("[Helm 3] Config directory: %s\n", v3ConfigDir)
 	v3DataDir := v3.DataDir()
-	log.Printf("[Helm 3] Data directory: %s\n", v3DataDir)
+	logger.Infof("[Helm 3] Data directory: %s\n", v3DataDir)
 	v3CacheDir := v3.CacheDir()
-	log.Printf("[Helm 3] Cache directory: %s\n", v3CacheDir)
+	logger.Infof("[Helm 3] Cache directory: %s\n", v3CacheDir)
 
 	// Create Helm v3 config directory if needed
-	log.Printf("[Helm 3] Create config folder \"%s\" .\n", v3ConfigDir)
+	logger.Infof("[Helm 3] Create config folder \"%s\" .\n", v3ConfigDir)
 	var err error
 	if !dryRun {
 		err = ensureDir(v3ConfigDir)
 		if err != nil {
 			return fmt.Errorf("[Helm 3] Failed to create config folder \"%s\" due to the following error: %s", v3ConfigDir, err)
 		}
-		log.Printf("[Helm 3] Config folder \"%s\" created.\n", v3ConfigDir)
+		logger.Infof("[Helm 3] Config folder \"%s\" created.\n", v3ConfigDir)
 	}
 
 	// Move repo config
 	v2RepoConfig := filepath.Join(v2HomeDir, "repository", "repositories.yaml")
 	v3RepoConfig := filepath.Join(v3ConfigDir, "repositories.yaml")
-	log.Printf("[Helm 2] repositories file \"%s\" will copy to [Helm 3] config folder \"%s\" .\n", v2RepoConfig, v3RepoConfig)
+	logger.Infof("[Helm 2] repositories file \"%s\" will copy to [Helm 3] config folder \"%s\" .\n", v2RepoConfig, v3RepoConfig)
 	if !dryRun {
 		err = copyFile(v2RepoConfig, v3RepoConfig)
 		if err != nil {
 			return fmt.Errorf("Failed to copy [Helm 2] repository file \"%s\" due to the following error: %s", v2RepoConfig, err)
 		}
-		log.Printf("[Helm 2] repositories file \"%s\" copied successfully to [Helm 3] config folder \"%s\" .\n", v2RepoConfig, v3RepoConfig)
+		logger.Infof("[Helm 2] repositories file \"%s\" copied successfully to [Helm 3] config folder \"%s\" .\n", v2RepoConfig, v3RepoConfig)
 	}
 
 	// Not moving local repo and its cache, as it is safer to recreate: e.g. v2HomeDir/repository/local v2HomeDir/repository/cache
 
 	// Create Helm v3 cache directory if needed
-	log.Printf("[Helm 3] Create cache folder \"%s\" .\n", v3CacheDir)
+	logger.Infof("[Helm 3] Create cache folder \"%s\" .\n", v3CacheDir)
 	if !dryRun {
 		err = ensureDir(v3CacheDir)
 		if err != nil {
 			return fmt.Errorf("[Helm 3] Failed to create cache folder \"%s\" due to the following error: %s", v3CacheDir, err)
 		}
-		log.Printf("[Helm 3] cache folder \"%s\" created.\n", v3CacheDir)
+		logger.Infof("[Helm 3] cache folder \"%s\" created.\n", v3CacheDir)
 	}
 
 	// Create Helm v3 data directory if needed
-	log.Printf("[Helm 3] Create data folder \"%s\" .\n", v3DataDir)
+	logger.Infof("[Helm 3] Create data folder \"%s\" .\n", v3DataDir)
 	if !dryRun {
 		err = ensureDir(v3DataDir)
 		if err != nil {
 			return fmt.Errorf("[Helm 3] Failed to create data folder \"%s\" due to the following error: %s", v3DataDir, err)
 		}
-		log.Printf("[Helm 3] data folder \"%s\" created.\n", v3DataDir)
+		logger.Infof("[Helm 3] data folder \"%s\" created.\n", v3DataDir)
 	}
 
 	// Handle plugins
@@ -95,37 +95,37 @@ func Copyv2HomeTov3(dryRun bool) error {
 		// Move plugins
 		v2Plugins := filepath.Join(v2HomeDir, "cache", "plugins")
 		v3Plugins := filepath.Join(v3CacheDir, "plugins")
-		log.Printf("[Helm 2] plugins \"%s\" will copy to [Helm 3] cache folder \"%s\" .\n", v2Plugins, v3Plugins)
+		logger.Infof("[Helm 2] plugins \"%s\" will copy to [Helm 3] cache folder \"%s\" .\n", v2Plugins, v3Plugins)
 		if !dryRun {
 			err = copyDir(v2Plugins, v3Plugins)
 			if err != nil {
 				return fmt.Errorf("Failed to copy [Helm 2] plugins directory \"%s\" due to the following error: %s", v2Plugins, err)
 			}
-			log.Printf("[Helm 2] plugins \"%s\" copied successfully to [Helm 3] cache folder \"%s\" .\n", v2Plugins, v3Plugins)
+			logger.Infof("[Helm 2] plugins \"%s\" copied successfully to [Helm 3] cache folder \"%s\" .\n", v2Plugins, v3Plugins)
 		}
 
 		// Recreate the  plugin symbolic links for v3 path
 		v2Links := filepath.Join(v2HomeDir, "plugins")
-		log.Printf("[Helm 2] plugin symbolic links \"%s\" will copy to [Helm 3] data folder \"%s\" .\n", v2Links, v3DataDir)
+		logger.Infof("[Helm 2] plugin symbolic links \"%s\" will copy to [Helm 3] data folder \"%s\" .\n", v2Links, v3DataDir)
 		if !dryRun {
 			err = reCreatePluginSymLinks(v2Links, v3DataDir, v3CacheDir)
 			if err != nil {
 				return fmt.Errorf("Failed to copy [Helm 2] plugin links \"%s\" due to the following error: %s", v2Links, err)
 			}
-			log.Printf("[Helm 2] plugin links \"%s\" copied successfully to [Helm 3] data folder \"%s\" .\n", v2Links, v3DataDir)
+			logger.Infof("[Helm 2] plugin links \"%s\" copied successfully to [Helm 3] data folder \"%s\" .\n", v2Links, v3DataDir)
 		}
 	}
 
 	// Move starters
 	v2Starters := filepath.Join(v2HomeDir, "starters")
 	v3Starters := filepath.Join(v3DataDir, "starters")
-	log.Printf("[Helm 2] starters \"%s\" will copy to [Helm 3] data folder \"%s\" .\n", v2Starters, v3Starters)
+	logger.Infof("[Helm 2] starters \"%s\" will copy to [Helm 3] data folder \"%s\" .\n", v2Starters, v3Starters)
 	if !dryRun {
 		err = copyDir(v2Starters, v3Starters)
 		if err != nil {
 			return fmt.Errorf("Failed to copy [Helm 2] starters \"%s\" due to the following error: %s", v2Starters, err)
 		}
-		log.Printf("[Helm 2] starters \"%s\" copied successfully to [Helm 3] data folder \"%s\" .\n", v2Starters, v3Starters)
+		logger.Infof("[Helm 2] starters \"%s\" copied successfully to [Helm 3] data folder \"%s\" .\n", v2Starters, v3Starters)
 	}
 
 	return nil
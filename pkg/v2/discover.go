@@ -0,0 +1,238 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+)
+
+// Tiller is a Tiller installation found in a namespace, or the Helm v2 storage left in a
+// namespace by a Tiller which was removed
+type Tiller struct {
+	Namespace string `json:"namespace"`
+	// Deployment is the name of the Tiller Deployment, empty if the Tiller is orphaned
+	Deployment string `json:"deployment,omitempty"`
+	// Service is the name of the Service selecting the Tiller pods, empty if none was found
+	Service string `json:"service,omitempty"`
+	// Version is the image tag of the Tiller Deployment, for example v2.16.12
+	Version     string `json:"version,omitempty"`
+	StorageType string `json:"storageType"`
+	Releases    int    `json:"releases"`
+	// ReleaseVersions is the number of release versions in the storage
+	ReleaseVersions int `json:"releaseVersions"`
+	// Orphaned is true if release versions are stored in the namespace but no Tiller Deployment was found
+	Orphaned bool `json:"orphaned"`
+}
+
+// tillerStorageObjects are the names of the storage objects found in a namespace and the
+// release names in their NAME label
+type tillerStorageObjects struct {
+	configMaps   map[string]bool
+	secrets      map[string]bool
+	releaseNames map[string]bool
+}
+
+// DiscoverTillers returns the Tillers of all namespaces, sorted by namespace. A Tiller is found from
+// its Deployment, by the Tiller labels or image, or from the storage objects which have the Tiller label,
+// like OWNER=TILLER, when the Deployment was removed. The storage type is 'both' if release versions
+// are stored in both ConfigMaps and Secrets, else the one set on the Deployment or the one found.
+func DiscoverTillers(tillerLabel string, kubeConfig common.KubeConfig) ([]Tiller, error) {
+	if tillerLabel == "" {
+		tillerLabel = "OWNER=TILLER"
+	}
+	cs := GetClientSet(kubeConfig)
+
+	deployments, err := cs.AppsV1().Deployments(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("[Helm 2] Failed to list Deployments in all namespaces due to the following error: %s", err)
+	}
+	tillerDeployments := map[string]*appsv1.Deployment{}
+	for i := range deployments.Items {
+		deployment := &deployments.Items[i]
+		if _, ok := getTillerContainer(deployment); !ok {
+			continue
+		}
+		// Deployments are listed by namespace and name, so the first one is kept
+		if _, ok := tillerDeployments[deployment.Namespace]; !ok {
+			tillerDeployments[deployment.Namespace] = deployment
+		}
+	}
+
+	services, err := cs.CoreV1().Services(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("[Helm 2] Failed to list Services in all namespaces due to the following error: %s", err)
+	}
+	tillerServices := map[string]string{}
+	for _, service := range services.Items {
+		deployment, ok := tillerDeployments[service.Namespace]
+		if !ok || !selectsPods(service.Spec.Selector, deployment.Spec.Template.Labels) {
+			continue
+		}
+		// Services are listed by namespace and name, so the first one is kept
+		if _, ok := tillerServices[service.Namespace]; !ok {
+			tillerServices[service.Namespace] = service.Name
+		}
+	}
+
+	storageObjects := map[string]*tillerStorageObjects{}
+	getStorageObjects := func(namespace string) *tillerStorageObjects {
+		if _, ok := storageObjects[namespace]; !ok {
+			storageObjects[namespace] = &tillerStorageObjects{
+				configMaps:   map[string]bool{},
+				secrets:      map[string]bool{},
+				releaseNames: map[string]bool{},
+			}
+		}
+		return storageObjects[namespace]
+	}
+	configMaps, err := cs.CoreV1().ConfigMaps(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{LabelSelector: tillerLabel})
+	if err != nil {
+		return nil, fmt.Errorf("[Helm 2] Failed to list ConfigMaps in all namespaces due to the following error: %s", err)
+	}
+	for _, configMap := range configMaps.Items {
+		objects := getStorageObjects(configMap.Namespace)
+		objects.configMaps[configMap.Name] = true
+		objects.releaseNames[configMap.Labels["NAME"]] = true
+	}
+	secrets, err := cs.CoreV1().Secrets(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{LabelSelector: tillerLabel})
+	if err != nil {
+		return nil, fmt.Errorf("[Helm 2] Failed to list Secrets in all namespaces due to the following error: %s", err)
+	}
+	for _, secret := range secrets.Items {
+		objects := getStorageObjects(secret.Namespace)
+		objects.secrets[secret.Name] = true
+		objects.releaseNames[secret.Labels["NAME"]] = true
+	}
+
+	namespaces := []string{}
+	for namespace := range tillerDeployments {
+		namespaces = append(namespaces, namespace)
+	}
+	for namespace := range storageObjects {
+		if _, ok := tillerDeployments[namespace]; !ok {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+
+	tillers := []Tiller{}
+	for _, namespace := range namespaces {
+		tiller := Tiller{Namespace: namespace}
+		objects := getStorageObjects(namespace)
+		if deployment, ok := tillerDeployments[namespace]; ok {
+			container, _ := getTillerContainer(deployment)
+			tiller.Deployment = deployment.Name
+			tiller.Service = tillerServices[namespace]
+			tiller.Version = getImageTag(container.Image)
+			tiller.StorageType = getContainerStorage(container)
+		} else {
+			tiller.Orphaned = true
+			tiller.StorageType = StorageConfigMaps
+			if len(objects.secrets) > 0 {
+				tiller.StorageType = StorageSecrets
+			}
+		}
+		if len(objects.configMaps) > 0 && len(objects.secrets) > 0 {
+			tiller.StorageType = StorageBoth
+		}
+
+		releaseVersions := map[string]bool{}
+		for name := range objects.configMaps {
+			releaseVersions[name] = true
+		}
+		for name := range objects.secrets {
+			releaseVersions[name] = true
+		}
+		tiller.Releases = len(objects.releaseNames)
+		tiller.ReleaseVersions = len(releaseVersions)
+		tillers = append(tillers, tiller)
+	}
+
+	return tillers, nil
+}
+
+// DiscoverTiller returns the Tiller of a namespace as DiscoverTillers finds it. It returns an error
+// if no Tiller Deployment is found in the namespace.
+func DiscoverTiller(tillerNamespace, tillerLabel string, kubeConfig common.KubeConfig) (Tiller, error) {
+	if tillerNamespace == "" {
+		tillerNamespace = "kube-system"
+	}
+	tillers, err := DiscoverTillers(tillerLabel, kubeConfig)
+	if err != nil {
+		return Tiller{}, err
+	}
+	for _, tiller := range tillers {
+		if tiller.Namespace == tillerNamespace && !tiller.Orphaned {
+			return tiller, nil
+		}
+	}
+	return Tiller{}, fmt.Errorf("[Helm 2] Tiller Deployment not found in \"%s\" namespace", tillerNamespace)
+}
+
+// getTillerContainer returns the Tiller container of a Deployment. A Deployment is a Tiller if it has
+// the labels set by 'helm init', app=helm and name=tiller, or a container running a Tiller image.
+func getTillerContainer(deployment *appsv1.Deployment) (corev1.Container, bool) {
+	containers := deployment.Spec.Template.Spec.Containers
+	for _, container := range containers {
+		image := container.Image
+		if i := strings.LastIndex(image, "/"); i >= 0 {
+			image = image[i+1:]
+		}
+		if strings.HasPrefix(image, "tiller:") || image == "tiller" {
+			return container, true
+		}
+	}
+	labels := deployment.Labels
+	if labels["app"] == "helm" && labels["name"] == "tiller" && len(containers) > 0 {
+		return containers[0], true
+	}
+	return corev1.Container{}, false
+}
+
+// selectsPods returns true if a Service selector is set and matches the labels of a pod template
+func selectsPods(selector, podLabels map[string]string) bool {
+	if len(selector) <= 0 {
+		return false
+	}
+	for key, value := range selector {
+		if podLabels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// getImageTag returns the tag of a container image, or an empty string if it has none
+func getImageTag(image string) string {
+	if i := strings.Index(image, "@"); i >= 0 {
+		image = image[:i]
+	}
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i+1:], "/") {
+		return ""
+	}
+	return image[i+1:]
+}
@@ -0,0 +1,61 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+)
+
+func TestDiscoverTillers(t *testing.T) {
+	podLabels := map[string]string{"app": "tiller-custom"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "tiller-custom", Namespace: "team-a"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: podLabels},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "tiller", Image: "gcr.io/kubernetes-helm/tiller:v2.16.12"}},
+				},
+			},
+		},
+	}
+	services := []*corev1.Service{
+		{ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "team-a"}, Spec: corev1.ServiceSpec{Selector: map[string]string{"app": "other"}}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "tiller-custom-svc", Namespace: "team-a"}, Spec: corev1.ServiceSpec{Selector: podLabels}},
+	}
+	cs := fake.NewSimpleClientset(deployment, services[0], services[1])
+	SetClientSet(cs)
+	defer SetClientSet(nil)
+
+	tillers, err := DiscoverTillers("", common.KubeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tillers) != 1 {
+		t.Fatalf("DiscoverTillers() found %d Tillers, want 1", len(tillers))
+	}
+	if tillers[0].Deployment != "tiller-custom" || tillers[0].Service != "tiller-custom-svc" {
+		t.Errorf("DiscoverTillers() found Deployment %q and Service %q, want \"tiller-custom\" and \"tiller-custom-svc\"", tillers[0].Deployment, tillers[0].Service)
+	}
+}
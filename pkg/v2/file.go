@@ -0,0 +1,116 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+
+	rls "k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// GetAllReleaseVersionsFromFile returns all release versions from a file of Helm v2 storage objects
+// grouped by release name. The file holds ConfigMaps or Secrets, or lists of them, as YAML or JSON
+// documents, e.g. as dumped by 'kubectl get configmaps -l OWNER=TILLER -o yaml'.
+func GetAllReleaseVersionsFromFile(fileName string) (map[string][]*rls.Release, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var releases []*rls.Release
+	decoder := utilyaml.NewYAMLOrJSONDecoder(file, 4096)
+	for {
+		var doc json.RawMessage
+		if err := decoder.Decode(&doc); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return nil, fmt.Errorf("Failed to parse file \"%s\" due to the following error: %s", fileName, err)
+		}
+		docReleases, err := getReleasesFromObject(doc)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read release versions from file \"%s\" due to the following error: %s", fileName, err)
+		}
+		releases = append(releases, docReleases...)
+	}
+
+	sort.Sort(ByReleaseVersion(releases))
+
+	// Releases are sorted by version so each group is also sorted by version
+	groupedReleases := make(map[string][]*rls.Release)
+	for _, release := range releases {
+		groupedReleases[release.Name] = append(groupedReleases[release.Name], release)
+	}
+
+	return groupedReleases, nil
+}
+
+func getReleasesFromObject(data json.RawMessage) ([]*rls.Release, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	var typeMeta metav1.TypeMeta
+	if err := json.Unmarshal(data, &typeMeta); err != nil {
+		return nil, err
+	}
+
+	var releases []*rls.Release
+	switch typeMeta.Kind {
+	case "List", "ConfigMapList", "SecretList":
+		var list struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(data, &list); err != nil {
+			return nil, err
+		}
+		for _, item := range list.Items {
+			itemReleases, err := getReleasesFromObject(item)
+			if err != nil {
+				return nil, err
+			}
+			releases = append(releases, itemReleases...)
+		}
+	case "ConfigMap":
+		var configMap corev1.ConfigMap
+		if err := json.Unmarshal(data, &configMap); err != nil {
+			return nil, err
+		}
+		if release := getRelease(configMap.Data["release"]); release != nil {
+			releases = append(releases, release)
+		}
+	case "Secret":
+		var secret corev1.Secret
+		if err := json.Unmarshal(data, &secret); err != nil {
+			return nil, err
+		}
+		if release := getRelease((string)(secret.Data["release"])); release != nil {
+			releases = append(releases, release)
+		}
+	default:
+		return nil, fmt.Errorf("kind \"%s\" is not a Helm v2 storage object", typeMeta.Kind)
+	}
+	return releases, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	rls "k8s.io/helm/pkg/proto/hapi/release"
+)
+
+type FilterOptions struct {
+	ChartName   string
+	NamePattern string
+	Namespace   string
+	Statuses    []string
+}
+
+// IsEmpty returns true if no selector is set
+func (filterOpts FilterOptions) IsEmpty() bool {
+	return filterOpts.ChartName == "" && filterOpts.NamePattern == "" && filterOpts.Namespace == "" && len(filterOpts.Statuses) == 0
+}
+
+// FilterReleases returns the releases which match all the selectors set in the filter options.
+// The releases are grouped by release name as returned by GetAllReleaseVersions. The selectors
+// are evaluated against the latest version of each release.
+func FilterReleases(releases map[string][]*rls.Release, filterOpts FilterOptions) (map[string][]*rls.Release, error) {
+	if filterOpts.NamePattern != "" {
+		if _, err := path.Match(filterOpts.NamePattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid release name pattern \"%s\": %s", filterOpts.NamePattern, err)
+		}
+	}
+	statuses, err := parseStatuses(filterOpts.Statuses)
+	if err != nil {
+		return nil, err
+	}
+
+	filteredReleases := make(map[string][]*rls.Release)
+	for releaseName, versions := range releases {
+		if len(versions) <= 0 {
+			continue
+		}
+		latest := versions[len(versions)-1]
+		if filterOpts.NamePattern != "" {
+			if matched, _ := path.Match(filterOpts.NamePattern, releaseName); !matched {
+				continue
+			}
+		}
+		if filterOpts.Namespace != "" && latest.Namespace != filterOpts.Namespace {
+			continue
+		}
+		if filterOpts.ChartName != "" && GetChartName(latest) != filterOpts.ChartName {
+			continue
+		}
+		if len(statuses) > 0 && !statuses[GetStatus(latest)] {
+			continue
+		}
+		filteredReleases[releaseName] = versions
+	}
+
+	return filteredReleases, nil
+}
+
+// parseStatuses returns the set of upper cased release statuses, for example DEPLOYED
+func parseStatuses(statusList []string) (map[string]bool, error) {
+	statuses := map[string]bool{}
+	for _, status := range statusList {
+		status = strings.ToUpper(strings.TrimSpace(status))
+		if _, ok := rls.Status_Code_value[status]; !ok {
+			return nil, fmt.Errorf("invalid release status \"%s\"", status)
+		}
+		statuses[status] = true
+	}
+	return statuses, nil
+}
+
+// GetChartName returns the name of the chart of a release version
+func GetChartName(release *rls.Release) string {
+	if release.Chart == nil || release.Chart.Metadata == nil {
+		return ""
+	}
+	return release.Chart.Metadata.Name
+}
+
+// GetStatus returns the status of a release version, for example DEPLOYED
+func GetStatus(release *rls.Release) string {
+	if release.Info == nil || release.Info.Status == nil {
+		return rls.Status_UNKNOWN.String()
+	}
+	return release.Info.Status.Code.String()
+}
@@ -0,0 +1,132 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	rls "k8s.io/helm/pkg/proto/hapi/release"
+
+	"github.com/helm/helm-2to3/pkg/logger"
+)
+
+type HistoryOptions struct {
+	// KeepFirstInstall keeps the first version of the release in addition to the latest versions,
+	// unless it is excluded by the other options
+	KeepFirstInstall   bool
+	MaxReleaseVersions int
+	OnlyDeployed       bool
+	// Since keeps only the versions last deployed at or after this time, when set
+	Since        time.Time
+	SkipStatuses []string
+}
+
+// SelectReleaseVersions returns the versions of a release to convert as per the history options.
+// The release versions need to be sorted by version. The latest deployed version is always
+// selected, so the DEPLOYED status cannot be skipped. Any other selected deployed version is returned
+// as a copy changed to superseded, so that exactly one selected version is deployed when the release
+// has a deployed version. The release versions passed are not changed. An error is returned if no
+// version is selected, as when a release without deployed version has all its versions excluded.
+func SelectReleaseVersions(releases []*rls.Release, histOpts HistoryOptions) ([]*rls.Release, error) {
+	skipStatuses, err := parseStatuses(histOpts.SkipStatuses)
+	if err != nil {
+		return nil, err
+	}
+	if skipStatuses[rls.Status_DEPLOYED.String()] {
+		return nil, errors.New("the DEPLOYED status cannot be skipped as the deployed version of a release is always converted")
+	}
+
+	var latestDeployed *rls.Release
+	for _, release := range releases {
+		if GetStatus(release) == rls.Status_DEPLOYED.String() {
+			latestDeployed = release
+		}
+	}
+
+	candidates := []*rls.Release{}
+	for _, release := range releases {
+		if histOpts.OnlyDeployed && release != latestDeployed {
+			continue
+		}
+		if skipStatuses[GetStatus(release)] {
+			continue
+		}
+		if !histOpts.Since.IsZero() && getLastDeployed(release).Before(histOpts.Since) {
+			continue
+		}
+		candidates = append(candidates, release)
+	}
+
+	if histOpts.KeepFirstInstall && len(releases) > 0 && releases[0] != latestDeployed && (len(candidates) == 0 || candidates[0] != releases[0]) {
+		firstInstall := releases[0]
+		versionLog := logger.With(logger.Fields{logger.FieldRelease: firstInstall.Name, logger.FieldVersion: firstInstall.Version})
+		versionLog.Warnf("[Helm 2] ReleaseVersion \"%s\" is the first install but is not kept as it is excluded by the history flags.\n", GetReleaseVersionName(firstInstall.Name, firstInstall.Version))
+	}
+
+	// Limit is based on newest versions
+	selected := candidates
+	if histOpts.MaxReleaseVersions > 0 && histOpts.MaxReleaseVersions < len(candidates) {
+		selected = append([]*rls.Release{}, candidates[len(candidates)-histOpts.MaxReleaseVersions:]...)
+		if histOpts.KeepFirstInstall && candidates[0] == releases[0] {
+			selected = append(selected, releases[0])
+		}
+	}
+
+	if latestDeployed != nil && !containsRelease(selected, latestDeployed) {
+		versionLog := logger.With(logger.Fields{logger.FieldRelease: latestDeployed.Name, logger.FieldVersion: latestDeployed.Version})
+		versionLog.Warnf("[Helm 2] ReleaseVersion \"%s\" is excluded by the history flags but is selected as it is the deployed version.\n", GetReleaseVersionName(latestDeployed.Name, latestDeployed.Version))
+		selected = append(selected, latestDeployed)
+	}
+	for i, release := range selected {
+		if release != latestDeployed && GetStatus(release) == rls.Status_DEPLOYED.String() {
+			logger.Infof("[Helm 2] ReleaseVersion \"%s\" is also deployed and will be converted as superseded.\n", GetReleaseVersionName(release.Name, release.Version))
+			superseded := proto.Clone(release).(*rls.Release)
+			superseded.Info.Status.Code = rls.Status_SUPERSEDED
+			selected[i] = superseded
+		}
+	}
+
+	if len(selected) == 0 {
+		if len(releases) == 0 {
+			return nil, errors.New("no release versions to select")
+		}
+		return nil, fmt.Errorf("no release versions of release \"%s\" are selected by the history flags, as it has no deployed version", releases[0].Name)
+	}
+
+	sort.Sort(ByReleaseVersion(selected))
+	return selected, nil
+}
+
+func getLastDeployed(release *rls.Release) time.Time {
+	if release.Info == nil || release.Info.LastDeployed == nil {
+		return time.Time{}
+	}
+	return time.Unix(release.Info.LastDeployed.Seconds, int64(release.Info.LastDeployed.Nanos))
+}
+
+func containsRelease(releases []*rls.Release, release *rls.Release) bool {
+	for _, r := range releases {
+		if r == release {
+			return true
+		}
+	}
+	return false
+}
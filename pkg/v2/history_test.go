@@ -0,0 +1,179 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/timestamp"
+	rls "k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// newHistory returns the versions of a release with the statuses, deployed a day apart from 2020-01-01
+func newHistory(statuses ...rls.Status_Code) []*rls.Release {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	releases := []*rls.Release{}
+	for i, status := range statuses {
+		releases = append(releases, &rls.Release{
+			Name:    "app",
+			Version: int32(i + 1),
+			Info: &rls.Info{
+				Status:       &rls.Status{Code: status},
+				LastDeployed: &timestamp.Timestamp{Seconds: start.AddDate(0, 0, i).Unix()},
+			},
+		})
+	}
+	return releases
+}
+
+// describe returns the versions and statuses of release versions, as "1:SUPERSEDED 2:DEPLOYED"
+func describe(releases []*rls.Release) string {
+	description := ""
+	for i, release := range releases {
+		if i > 0 {
+			description += " "
+		}
+		description += fmt.Sprintf("%d:%s", release.Version, GetStatus(release))
+	}
+	return description
+}
+
+func TestSelectReleaseVersions(t *testing.T) {
+	const (
+		deployed   = rls.Status_DEPLOYED
+		superseded = rls.Status_SUPERSEDED
+		failed     = rls.Status_FAILED
+	)
+	tests := []struct {
+		name     string
+		releases []*rls.Release
+		options  HistoryOptions
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "all",
+			releases: newHistory(superseded, superseded, deployed),
+			want:     "1:SUPERSEDED 2:SUPERSEDED 3:DEPLOYED",
+		},
+		{
+			name:     "max",
+			releases: newHistory(superseded, superseded, superseded, deployed),
+			options:  HistoryOptions{MaxReleaseVersions: 2},
+			want:     "3:SUPERSEDED 4:DEPLOYED",
+		},
+		{
+			name:     "max keeps the deployed version",
+			releases: newHistory(superseded, deployed, failed, failed),
+			options:  HistoryOptions{MaxReleaseVersions: 1},
+			want:     "2:DEPLOYED 4:FAILED",
+		},
+		{
+			name:     "keep first install",
+			releases: newHistory(superseded, superseded, superseded, deployed),
+			options:  HistoryOptions{MaxReleaseVersions: 2, KeepFirstInstall: true},
+			want:     "1:SUPERSEDED 3:SUPERSEDED 4:DEPLOYED",
+		},
+		{
+			name:     "keep first install excluded by since",
+			releases: newHistory(superseded, superseded, superseded, deployed),
+			options:  HistoryOptions{MaxReleaseVersions: 1, KeepFirstInstall: true, Since: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+			want:     "4:DEPLOYED",
+		},
+		{
+			name:     "since",
+			releases: newHistory(superseded, superseded, deployed),
+			options:  HistoryOptions{Since: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+			want:     "2:SUPERSEDED 3:DEPLOYED",
+		},
+		{
+			name:     "since keeps the deployed version",
+			releases: newHistory(deployed, failed),
+			options:  HistoryOptions{Since: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+			want:     "1:DEPLOYED 2:FAILED",
+		},
+		{
+			name:     "skip status",
+			releases: newHistory(superseded, failed, deployed),
+			options:  HistoryOptions{SkipStatuses: []string{"failed"}},
+			want:     "1:SUPERSEDED 3:DEPLOYED",
+		},
+		{
+			name:     "skip deployed status",
+			releases: newHistory(superseded, deployed),
+			options:  HistoryOptions{SkipStatuses: []string{"DEPLOYED"}},
+			wantErr:  true,
+		},
+		{
+			name:     "unknown skip status",
+			releases: newHistory(deployed),
+			options:  HistoryOptions{SkipStatuses: []string{"RUNNING"}},
+			wantErr:  true,
+		},
+		{
+			name:     "only deployed",
+			releases: newHistory(superseded, deployed, failed),
+			options:  HistoryOptions{OnlyDeployed: true},
+			want:     "2:DEPLOYED",
+		},
+		{
+			name:     "several deployed versions",
+			releases: newHistory(deployed, superseded, deployed),
+			want:     "1:SUPERSEDED 2:SUPERSEDED 3:DEPLOYED",
+		},
+		{
+			name:     "no deployed version",
+			releases: newHistory(superseded, failed),
+			want:     "1:SUPERSEDED 2:FAILED",
+		},
+		{
+			name:     "only deployed without deployed version",
+			releases: newHistory(superseded, failed),
+			options:  HistoryOptions{OnlyDeployed: true},
+			wantErr:  true,
+		},
+		{
+			name:     "all versions skipped without deployed version",
+			releases: newHistory(failed, failed),
+			options:  HistoryOptions{SkipStatuses: []string{"FAILED"}},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := describe(tt.releases)
+			selected, err := SelectReleaseVersions(tt.releases, tt.options)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("SelectReleaseVersions() = %s, want error", describe(selected))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := describe(selected); got != tt.want {
+				t.Errorf("SelectReleaseVersions() = %s, want %s", got, tt.want)
+			}
+			if after := describe(tt.releases); after != before {
+				t.Errorf("SelectReleaseVersions() changed the release versions passed from %s to %s", before, after)
+			}
+		})
+	}
+}
@@ -0,0 +1,140 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// MemoryStorage is a Helm v2 storage which holds the storage objects in memory, for example to
+// run commands without a cluster
+type MemoryStorage struct {
+	storageType string
+	objects     map[string]runtime.Object
+	mu          sync.Mutex
+}
+
+// NewMemoryStorage returns an in-memory storage of a type, 'configmaps' or 'secrets', which holds
+// the storage objects. The objects need to be ConfigMaps or Secrets as per the storage type.
+func NewMemoryStorage(storageType string, objects ...runtime.Object) (*MemoryStorage, error) {
+	if storageType != StorageConfigMaps && storageType != StorageSecrets {
+		return nil, fmt.Errorf("Helm v2 storage type \"%s\" is not supported, it needs to be '%s' or '%s'", storageType, StorageConfigMaps, StorageSecrets)
+	}
+	storage := &MemoryStorage{
+		storageType: storageType,
+		objects:     map[string]runtime.Object{},
+	}
+	for _, object := range objects {
+		if err := storage.Add(object); err != nil {
+			return nil, err
+		}
+	}
+	return storage, nil
+}
+
+// Type returns the storage type, 'configmaps' or 'secrets'
+func (storage *MemoryStorage) Type() string {
+	return storage.storageType
+}
+
+// Add adds or replaces a storage object
+func (storage *MemoryStorage) Add(object runtime.Object) error {
+	switch object.(type) {
+	case *corev1.ConfigMap:
+		if storage.storageType != StorageConfigMaps {
+			return fmt.Errorf("ConfigMap cannot be added to Helm v2 storage of type \"%s\"", storage.storageType)
+		}
+	case *corev1.Secret:
+		if storage.storageType != StorageSecrets {
+			return fmt.Errorf("Secret cannot be added to Helm v2 storage of type \"%s\"", storage.storageType)
+		}
+	default:
+		return fmt.Errorf("storage object of type %T is not supported", object)
+	}
+	accessor, err := meta.Accessor(object)
+	if err != nil {
+		return err
+	}
+
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	storage.objects[accessor.GetName()] = object.DeepCopyObject()
+	return nil
+}
+
+// List returns the storage objects which match a label selector, sorted by name
+func (storage *MemoryStorage) List(selector string) ([]runtime.Object, error) {
+	labelSelector, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	names := []string{}
+	for name := range storage.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var objects []runtime.Object
+	for _, name := range names {
+		object := storage.objects[name]
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, err
+		}
+		if labelSelector.Matches(labels.Set(accessor.GetLabels())) {
+			objects = append(objects, object.DeepCopyObject())
+		}
+	}
+	return objects, nil
+}
+
+// Get returns a storage object by name
+func (storage *MemoryStorage) Get(name string) (runtime.Object, error) {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	object, ok := storage.objects[name]
+	if !ok {
+		return nil, apierrors.NewNotFound(storage.resource(), name)
+	}
+	return object.DeepCopyObject(), nil
+}
+
+// Delete deletes a storage object by name
+func (storage *MemoryStorage) Delete(name string) error {
+	storage.mu.Lock()
+	defer storage.mu.Unlock()
+	if _, ok := storage.objects[name]; !ok {
+		return apierrors.NewNotFound(storage.resource(), name)
+	}
+	delete(storage.objects, name)
+	return nil
+}
+
+func (storage *MemoryStorage) resource() schema.GroupResource {
+	return corev1.Resource(storage.storageType)
+}
@@ -0,0 +1,236 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/golang/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/helm/helm-2to3/pkg/logger"
+)
+
+// MergedStorage is the Helm v2 storage of a Tiller which was switched from ConfigMaps to Secrets,
+// or back, so that the versions of a release are split across both. The release versions of both
+// are merged by name, which holds the release name and version.
+type MergedStorage struct {
+	configMaps Storage
+	secrets    Storage
+}
+
+// NewMergedStorage returns the storage merging the ConfigMaps and Secrets of a Tiller namespace
+func NewMergedStorage(cs kubernetes.Interface, namespace string) *MergedStorage {
+	return &MergedStorage{
+		configMaps: NewConfigMapStorage(cs, namespace),
+		secrets:    NewSecretStorage(cs, namespace),
+	}
+}
+
+// Type returns 'both'
+func (storage *MergedStorage) Type() string {
+	return StorageBoth
+}
+
+// List returns the storage objects of both storages which match a label selector, sorted by name.
+// A release version stored in both is returned once, as the Secret. The versions of a release with
+// a version stored in both with different content are left out, and the conflict is logged.
+func (storage *MergedStorage) List(selector string) ([]runtime.Object, error) {
+	objects, conflicts, err := storage.ListWithConflicts(selector)
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range conflicts {
+		logger.Warnf("[Helm 2] ReleaseVersion \"%s\" is stored in both a ConfigMap and a Secret with different content, its release is skipped. The release storage needs to be set to 'configmaps' or 'secrets' to choose one.\n", name)
+	}
+	return objects, nil
+}
+
+// ListWithConflicts returns the storage objects as List does, and the names of the release versions
+// stored in both a ConfigMap and a Secret with different content. All the versions of the releases
+// of these release versions are left out of the storage objects, so that no release is read with
+// versions from the wrong storage.
+func (storage *MergedStorage) ListWithConflicts(selector string) ([]runtime.Object, []string, error) {
+	configMaps, err := storage.configMaps.List(selector)
+	if err != nil {
+		return nil, nil, err
+	}
+	secrets, err := storage.secrets.List(selector)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	objectsByName := map[string]runtime.Object{}
+	for _, object := range configMaps {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, nil, err
+		}
+		objectsByName[accessor.GetName()] = object
+	}
+	conflicts := []string{}
+	conflictingReleases := map[string]bool{}
+	for _, object := range secrets {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, nil, err
+		}
+		name := accessor.GetName()
+		if configMap, ok := objectsByName[name]; ok && !isSameReleaseVersion(configMap, object) {
+			conflicts = append(conflicts, name)
+			conflictingReleases[accessor.GetLabels()["NAME"]] = true
+		}
+		objectsByName[name] = object
+	}
+
+	names := []string{}
+	for name, object := range objectsByName {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, nil, err
+		}
+		if conflictingReleases[accessor.GetLabels()["NAME"]] {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Strings(conflicts)
+	var objects []runtime.Object
+	for _, name := range names {
+		objects = append(objects, objectsByName[name])
+	}
+	return objects, conflicts, nil
+}
+
+// ListCopies returns the storage objects of both storages which match a label selector, sorted by
+// name, without merging them. A release version stored in both is returned once per kind, the
+// ConfigMap first, whether the copies differ or not.
+func (storage *MergedStorage) ListCopies(selector string) ([]runtime.Object, error) {
+	configMaps, err := storage.configMaps.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := storage.secrets.List(selector)
+	if err != nil {
+		return nil, err
+	}
+	objects := append(configMaps, secrets...)
+	names := make([]string, len(objects))
+	for i, object := range objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = accessor.GetName()
+	}
+	sort.Stable(byName{objects: objects, names: names})
+	return objects, nil
+}
+
+// byName sorts storage objects by name
+type byName struct {
+	objects []runtime.Object
+	names   []string
+}
+
+func (b byName) Len() int           { return len(b.objects) }
+func (b byName) Less(i, j int) bool { return b.names[i] < b.names[j] }
+func (b byName) Swap(i, j int) {
+	b.objects[i], b.objects[j] = b.objects[j], b.objects[i]
+	b.names[i], b.names[j] = b.names[j], b.names[i]
+}
+
+// Get returns a storage object by name, from the Secrets first
+func (storage *MergedStorage) Get(name string) (runtime.Object, error) {
+	object, err := storage.secrets.Get(name)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return object, err
+	}
+	return storage.configMaps.Get(name)
+}
+
+// Kinds returns the kinds of the storage objects of a name, 'ConfigMap' and 'Secret'
+func (storage *MergedStorage) Kinds(name string) ([]string, error) {
+	copies, err := storage.Copies(name)
+	if err != nil {
+		return nil, err
+	}
+	kinds := []string{}
+	for _, object := range copies {
+		switch object.(type) {
+		case *corev1.ConfigMap:
+			kinds = append(kinds, "ConfigMap")
+		case *corev1.Secret:
+			kinds = append(kinds, "Secret")
+		}
+	}
+	return kinds, nil
+}
+
+// Copies returns the storage objects of a name in both storages, the ConfigMap first
+func (storage *MergedStorage) Copies(name string) ([]runtime.Object, error) {
+	copies := []runtime.Object{}
+	for _, s := range []Storage{storage.configMaps, storage.secrets} {
+		object, err := s.Get(name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		copies = append(copies, object)
+	}
+	return copies, nil
+}
+
+// Delete deletes a storage object by name from both storages. It returns a NotFound error if the
+// object is in neither.
+func (storage *MergedStorage) Delete(name string) error {
+	var notFound error
+	found := false
+	for _, s := range []Storage{storage.configMaps, storage.secrets} {
+		err := s.Delete(name)
+		if apierrors.IsNotFound(err) {
+			notFound = err
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to delete %s \"%s\": %s", s.Type(), name, err)
+		}
+		found = true
+	}
+	if !found {
+		return notFound
+	}
+	return nil
+}
+
+// isSameReleaseVersion returns true if two storage objects hold the same release version
+func isSameReleaseVersion(object, other runtime.Object) bool {
+	release := decodeStorageObject(object)
+	otherRelease := decodeStorageObject(other)
+	if release == nil || otherRelease == nil {
+		return release == otherRelease
+	}
+	return proto.Equal(release, otherRelease)
+}
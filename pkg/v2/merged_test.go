@@ -0,0 +1,163 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	rls "k8s.io/helm/pkg/proto/hapi/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+)
+
+// encodeRelease encodes a release version as Tiller stores it
+func encodeRelease(t *testing.T, release *rls.Release) string {
+	data, err := proto.Marshal(release)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var compressed bytes.Buffer
+	gzipWriter := gzip.NewWriter(&compressed)
+	if _, err := gzipWriter.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := gzipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(compressed.Bytes())
+}
+
+// newMergedObjects returns the ConfigMap and Secret of a release version, the Secret holding the
+// manifest of secretManifest
+func newMergedObjects(t *testing.T, name string, secretManifest string) (*corev1.ConfigMap, *corev1.Secret) {
+	labels := map[string]string{"OWNER": "TILLER", "NAME": name}
+	meta := metav1.ObjectMeta{Name: name + ".v1", Namespace: "kube-system", Labels: labels}
+	release := encodeRelease(t, &rls.Release{Name: name, Version: 1, Manifest: "a"})
+	secretRelease := encodeRelease(t, &rls.Release{Name: name, Version: 1, Manifest: secretManifest})
+	return &corev1.ConfigMap{ObjectMeta: meta, Data: map[string]string{"release": release}},
+		&corev1.Secret{ObjectMeta: meta, Data: map[string][]byte{"release": []byte(secretRelease)}}
+}
+
+// objectNames returns the kinds and names of storage objects
+func objectNames(t *testing.T, objects []runtime.Object) []string {
+	names := []string{}
+	for _, object := range objects {
+		accessor, err := meta.Accessor(object)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, fmt.Sprintf("%T/%s", object, accessor.GetName()))
+	}
+	return names
+}
+
+func TestMergedStorage(t *testing.T) {
+	// The copies of app.v1 are the same, the copies of web.v1 differ
+	appConfigMap, appSecret := newMergedObjects(t, "app", "a")
+	webConfigMap, webSecret := newMergedObjects(t, "web", "b")
+	cs := fake.NewSimpleClientset(appConfigMap, appSecret, webConfigMap, webSecret)
+	storage := NewMergedStorage(cs, "kube-system")
+
+	objects, conflicts, err := storage.ListWithConflicts("OWNER=TILLER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, want := objectNames(t, objects), []string{"*v1.Secret/app.v1"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("ListWithConflicts() returned %v, want %v", names, want)
+	}
+	if want := []string{"web.v1"}; !reflect.DeepEqual(conflicts, want) {
+		t.Errorf("ListWithConflicts() returned conflicts %v, want %v", conflicts, want)
+	}
+
+	// List skips the release with conflicting copies
+	objects, err = storage.List("OWNER=TILLER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, want := objectNames(t, objects), []string{"*v1.Secret/app.v1"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("List() returned %v, want %v", names, want)
+	}
+
+	// ListCopies returns all the copies without comparing them
+	objects, err = storage.ListCopies("OWNER=TILLER")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"*v1.ConfigMap/app.v1", "*v1.Secret/app.v1", "*v1.ConfigMap/web.v1", "*v1.Secret/web.v1"}
+	if names := objectNames(t, objects); !reflect.DeepEqual(names, want) {
+		t.Errorf("ListCopies() returned %v, want %v", names, want)
+	}
+
+	copies, err := storage.Copies("web.v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, want := objectNames(t, copies), []string{"*v1.ConfigMap/web.v1", "*v1.Secret/web.v1"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Copies() returned %v, want %v", names, want)
+	}
+}
+
+func TestGetStorageObjectsWithConflicts(t *testing.T) {
+	appConfigMap, appSecret := newMergedObjects(t, "app", "a")
+	webConfigMap, webSecret := newMergedObjects(t, "web", "b")
+	SetClientSet(fake.NewSimpleClientset(appConfigMap, appSecret, webConfigMap, webSecret))
+	t.Cleanup(func() { SetClientSet(nil) })
+	retrieveOptions := RetrieveOptions{StorageType: StorageBoth, TillerOutCluster: true}
+
+	// Only the release with conflicting copies fails
+	retrieveOptions.ReleaseName = "web"
+	if _, _, err := GetStorageObjects(retrieveOptions, common.KubeConfig{}); err == nil || !strings.Contains(err.Error(), "web.v1") {
+		t.Errorf("GetStorageObjects() of release \"web\" returned %v, want an error naming web.v1", err)
+	}
+	retrieveOptions.ReleaseName = "app"
+	versions, err := GetReleaseVersions(retrieveOptions, common.KubeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(versions) != 1 || versions[0].Name != "app" {
+		t.Errorf("GetReleaseVersions() of release \"app\" returned %v", versions)
+	}
+	releases, err := GetAllReleaseVersions(retrieveOptions, common.KubeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := releases["web"]; ok || len(releases["app"]) != 1 {
+		t.Errorf("GetAllReleaseVersions() returned %v, want only release \"app\"", releases)
+	}
+
+	// A backup gets all the copies of the release with conflicting copies
+	retrieveOptions.ReleaseName = "web"
+	objects, storageType, err := GetStorageObjectCopies(retrieveOptions, common.KubeConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names, want := objectNames(t, objects), []string{"*v1.ConfigMap/web.v1", "*v1.Secret/web.v1"}; storageType != StorageBoth || !reflect.DeepEqual(names, want) {
+		t.Errorf("GetStorageObjectCopies() returned %v of storage \"%s\", want %v", names, storageType, want)
+	}
+}
@@ -19,15 +19,19 @@ package v2
 import (
 	"context"
 	"fmt"
-	"log"
 	"sort"
+	"strings"
 
 	utils "github.com/maorfr/helm-plugin-utils/pkg"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 
 	rls "k8s.io/helm/pkg/proto/hapi/release"
 
 	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
 )
 
 type RetrieveOptions struct {
@@ -55,7 +59,7 @@ func (releases ByReleaseVersion) Swap(i, j int) { releases[i], releases[j] = rel
 // GetReleaseVersions returns all release versions from Helm v2 storage for a specified release..
 // It is based on Tiller namespace and labels like owner of storage.
 func GetReleaseVersions(retOpts RetrieveOptions, kubeConfig common.KubeConfig) ([]*rls.Release, error) {
-	releases, err := getReleases(retOpts, kubeConfig)
+	releases, err := GetReleaseHistory(retOpts, kubeConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -67,17 +71,45 @@ func GetReleaseVersions(retOpts RetrieveOptions, kubeConfig common.KubeConfig) (
 
 }
 
+// GetReleaseHistory returns the release versions from Helm v2 storage for a specified release,
+// sorted by version. Unlike GetReleaseVersions, a release with no versions left is not an error.
+func GetReleaseHistory(retOpts RetrieveOptions, kubeConfig common.KubeConfig) ([]*rls.Release, error) {
+	if retOpts.ReleaseName == "" {
+		return nil, fmt.Errorf("release name is required to get the history of a release")
+	}
+	return getReleases(retOpts, kubeConfig)
+}
+
+// GetAllReleaseVersions returns all release versions from Helm v2 storage grouped by release name.
+// The storage is only listed once. It is based on Tiller namespace and labels like owner of storage.
+func GetAllReleaseVersions(retOpts RetrieveOptions, kubeConfig common.KubeConfig) (map[string][]*rls.Release, error) {
+	retOpts.ReleaseName = ""
+	releases, err := getReleases(retOpts, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Releases are sorted by version so each group is also sorted by version
+	groupedReleases := make(map[string][]*rls.Release)
+	for _, release := range releases {
+		groupedReleases[release.Name] = append(groupedReleases[release.Name], release)
+	}
+
+	return groupedReleases, nil
+}
+
 // DeleteReleaseVersions deletes all release data from Helm v2 storage for a specified release.
 // It is based on Tiller namespace and labels like owner of storage.
 func DeleteReleaseVersions(retOpts RetrieveOptions, delOpts DeleteOptions, kubeConfig common.KubeConfig) error {
 	for _, ver := range delOpts.Versions {
 		relVerName := fmt.Sprintf("%s.v%d", retOpts.ReleaseName, ver)
-		log.Printf("[Helm 2] ReleaseVersion \"%s\" will be deleted.\n", relVerName)
+		versionLog := deleteLogger(retOpts, retOpts.ReleaseName, ver)
+		versionLog.Infof("[Helm 2] ReleaseVersion \"%s\" will be deleted.\n", relVerName)
 		if !delOpts.DryRun {
 			if err := deleteRelease(retOpts, relVerName, kubeConfig); err != nil {
 				return fmt.Errorf("[Helm 2] ReleaseVersion \"%s\" failed to delete with error: %s.\n", relVerName, err)
 			}
-			log.Printf("[Helm 2] ReleaseVersion \"%s\" deleted.\n", relVerName)
+			versionLog.Infof("[Helm 2] ReleaseVersion \"%s\" deleted.\n", relVerName)
 		}
 	}
 
@@ -104,7 +136,7 @@ func DeleteAllReleaseVersions(retOpts RetrieveOptions, kubeConfig common.KubeCon
 	}
 	releaseLen := len(releases)
 	if releaseLen <= 0 {
-		log.Printf("[Helm 2] no deployed releases for namespace: %s, owner: %s\n", retOpts.TillerNamespace, retOpts.TillerLabel)
+		logger.Infof("[Helm 2] no deployed releases for namespace: %s, owner: %s\n", retOpts.TillerNamespace, retOpts.TillerLabel)
 		return nil
 	}
 
@@ -112,77 +144,155 @@ func DeleteAllReleaseVersions(retOpts RetrieveOptions, kubeConfig common.KubeCon
 	for i := 0; i < releaseLen; i++ {
 		release := releases[i]
 		relVerName := GetReleaseVersionName(release.Name, release.Version)
-		log.Printf("[Helm 2] ReleaseVersion \"%s\" will be deleted.\n", relVerName)
+		versionLog := deleteLogger(retOpts, release.Name, release.Version)
+		versionLog.Infof("[Helm 2] ReleaseVersion \"%s\" will be deleted.\n", relVerName)
 		if !dryRun {
 			if err := deleteRelease(retOpts, relVerName, kubeConfig); err != nil {
 				return fmt.Errorf("[Helm 2] ReleaseVersion \"%s\" failed to delete with error: %s.\n", relVerName, err)
 			}
-			log.Printf("[Helm 2] ReleaseVersion \"%s\" deleted.\n", relVerName)
+			versionLog.Infof("[Helm 2] ReleaseVersion \"%s\" deleted.\n", relVerName)
 		}
 	}
 	return nil
 }
 
+// deleteLogger returns the logger of a Helm v2 release version deletion. The namespace
+// is the Tiller namespace where the release version is stored.
+func deleteLogger(retOpts RetrieveOptions, releaseName string, version int32) *logger.Logger {
+	return logger.With(logger.Fields{
+		logger.FieldOperation: "delete-v2",
+		logger.FieldRelease:   releaseName,
+		logger.FieldVersion:   version,
+		logger.FieldNamespace: retOpts.TillerNamespace,
+	})
+}
+
 func getReleases(retOpts RetrieveOptions, kubeConfig common.KubeConfig) ([]*rls.Release, error) {
+	objects, _, err := GetStorageObjects(retOpts, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	var releases []*rls.Release
+	for _, object := range objects {
+		release := decodeStorageObject(object)
+		if release == nil {
+			continue
+		}
+		releases = append(releases, release)
+	}
+
+	sort.Sort(ByReleaseVersion(releases))
+
+	return releases, nil
+}
+
+// GetStorageObjects returns the Secrets or ConfigMaps of Helm v2 storage which hold the release
+// versions for a specified release, or for all releases if no release is specified. It also
+// returns the storage type. It is based on Tiller namespace and labels like owner of storage.
+func GetStorageObjects(retOpts RetrieveOptions, kubeConfig common.KubeConfig) ([]runtime.Object, string, error) {
+	retOpts, selector := storageSelector(retOpts)
+	storage, err := GetStorage(retOpts, kubeConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	// A release version stored in both a ConfigMap and a Secret with different content only fails
+	// the release it belongs to. When all releases are listed, the release is skipped instead.
+	if mergedStorage, ok := storage.(*MergedStorage); ok && retOpts.ReleaseName != "" {
+		objects, conflicts, err := mergedStorage.ListWithConflicts(selector)
+		if err != nil {
+			return nil, storage.Type(), err
+		}
+		if len(conflicts) > 0 {
+			return nil, storage.Type(), fmt.Errorf("[Helm 2] ReleaseVersions \"%s\" are stored in both a ConfigMap and a Secret with different content, the release storage needs to be set to 'configmaps' or 'secrets' to choose one", strings.Join(conflicts, "\", \""))
+		}
+		return objects, storage.Type(), nil
+	}
+	objects, err := storage.List(selector)
+	if err != nil {
+		return nil, storage.Type(), err
+	}
+
+	return objects, storage.Type(), nil
+}
+
+// GetStorageObjectCopies returns the storage objects as GetStorageObjects does, except that with the
+// 'both' storage type the ConfigMaps and Secrets are returned as they are, without merging or comparing
+// them, so that no copy is left out of a backup, even when the copies of a release version differ
+func GetStorageObjectCopies(retOpts RetrieveOptions, kubeConfig common.KubeConfig) ([]runtime.Object, string, error) {
+	retOpts, selector := storageSelector(retOpts)
+	storage, err := GetStorage(retOpts, kubeConfig)
+	if err != nil {
+		return nil, "", err
+	}
+	mergedStorage, ok := storage.(*MergedStorage)
+	if !ok {
+		return GetStorageObjects(retOpts, kubeConfig)
+	}
+	objects, err := mergedStorage.ListCopies(selector)
+	if err != nil {
+		return nil, storage.Type(), err
+	}
+	return objects, storage.Type(), nil
+}
+
+// storageSelector returns the retrieve options with the defaults set, and the label selector of the
+// storage objects of the release, or of all releases if no release is specified
+func storageSelector(retOpts RetrieveOptions) (RetrieveOptions, string) {
 	if retOpts.TillerNamespace == "" {
 		retOpts.TillerNamespace = "kube-system"
 	}
 	if retOpts.TillerLabel == "" {
 		retOpts.TillerLabel = "OWNER=TILLER"
 	}
+	if retOpts.StorageType == "" {
+		retOpts.StorageType = "configmaps"
+	}
+	selector := retOpts.TillerLabel
 	if retOpts.ReleaseName != "" {
-		retOpts.TillerLabel += fmt.Sprintf(",NAME=%s", retOpts.ReleaseName)
+		selector += fmt.Sprintf(",NAME=%s", retOpts.ReleaseName)
+	}
+	return retOpts, selector
+}
+
+// GetStorageType returns the Helm v2 storage type, 'secrets' or 'configmaps'. It is detected
+// from the Tiller deployment unless Tiller is running out of the cluster. With the 'auto' storage
+// type, it is detected from the storage objects found in the Tiller namespace instead. The 'both'
+// storage type is returned as is.
+func GetStorageType(retOpts RetrieveOptions, kubeConfig common.KubeConfig) (string, error) {
+	if retOpts.TillerNamespace == "" {
+		retOpts.TillerNamespace = "kube-system"
+	}
+	if retOpts.TillerLabel == "" {
+		retOpts.TillerLabel = "OWNER=TILLER"
 	}
 	if retOpts.StorageType == "" {
 		retOpts.StorageType = "configmaps"
 	}
-	storage := getStorageType(retOpts, kubeConfig)
-	clientSet := utils.GetClientSetWithKubeConfig(kubeConfig.File, kubeConfig.Context)
-	var releases []*rls.Release
-	switch storage {
-	case "secrets":
-		secrets, err := clientSet.CoreV1().Secrets(retOpts.TillerNamespace).List(context.Background(), metav1.ListOptions{
-			LabelSelector: retOpts.TillerLabel,
-		})
-		if err != nil {
-			return nil, err
-		}
-		for _, item := range secrets.Items {
-			release := getRelease((string)(item.Data["release"]))
-			if release == nil {
-				continue
-			}
-			releases = append(releases, release)
-		}
-	case "configmaps":
-		configMaps, err := clientSet.CoreV1().ConfigMaps(retOpts.TillerNamespace).List(context.Background(), metav1.ListOptions{
-			LabelSelector: retOpts.TillerLabel,
-		})
-		if err != nil {
-			return nil, err
-		}
-		for _, item := range configMaps.Items {
-			release := getRelease(item.Data["release"])
-			if release == nil {
-				continue
-			}
-			releases = append(releases, release)
-		}
+	if storageOverride != nil {
+		return storageOverride.Type(), nil
 	}
-
-	sort.Sort(ByReleaseVersion(releases))
-
-	return releases, nil
+	if retOpts.StorageType == StorageBoth {
+		return StorageBoth, nil
+	}
+	if retOpts.StorageType == StorageAuto {
+		return detectStorageType(GetClientSet(kubeConfig), retOpts.TillerNamespace, retOpts.TillerLabel)
+	}
+	if !retOpts.TillerOutCluster {
+		return getTillerStorage(GetClientSet(kubeConfig), retOpts.TillerNamespace)
+	}
+	return retOpts.StorageType, nil
 }
 
-func getStorageType(retOpts RetrieveOptions, kubeConfig common.KubeConfig) string {
-	var storage string
-	if !retOpts.TillerOutCluster {
-		storage = utils.GetTillerStorageWithKubeConfig(retOpts.TillerNamespace, kubeConfig.File, kubeConfig.Context)
-	} else {
-		storage = retOpts.StorageType
+// decodeStorageObject returns the release version held by a Secret or ConfigMap, or nil if it
+// cannot be decoded
+func decodeStorageObject(object runtime.Object) *rls.Release {
+	switch item := object.(type) {
+	case *corev1.Secret:
+		return getRelease((string)(item.Data["release"]))
+	case *corev1.ConfigMap:
+		return getRelease(item.Data["release"])
 	}
-	return storage
+	return nil
 }
 
 func getRelease(itemReleaseData string) *rls.Release {
@@ -197,13 +307,59 @@ func deleteRelease(retOpts RetrieveOptions, releaseVersionName string, kubeConfi
 	if retOpts.StorageType == "" {
 		retOpts.StorageType = "configmaps"
 	}
-	storage := getStorageType(retOpts, kubeConfig)
-	clientSet := utils.GetClientSetWithKubeConfig(kubeConfig.File, kubeConfig.Context)
-	switch storage {
-	case "secrets":
-		return clientSet.CoreV1().Secrets(retOpts.TillerNamespace).Delete(context.Background(), releaseVersionName, metav1.DeleteOptions{})
-	case "configmaps":
-		return clientSet.CoreV1().ConfigMaps(retOpts.TillerNamespace).Delete(context.Background(), releaseVersionName, metav1.DeleteOptions{})
+	storage, err := GetStorage(retOpts, kubeConfig)
+	if err != nil {
+		return err
 	}
-	return nil
+	return storage.Delete(releaseVersionName)
+}
+
+// RestoreStorageObject creates a Secret or ConfigMap holding a release version in Helm v2 storage.
+// If the object already exists, it is replaced when overwrite is set and skipped otherwise.
+// It returns whether the object already existed.
+func RestoreStorageObject(namespace string, object runtime.Object, overwrite, dryRun bool, kubeConfig common.KubeConfig) (bool, error) {
+	clientSet := GetClientSet(kubeConfig)
+	switch item := object.(type) {
+	case *corev1.Secret:
+		secret := item.DeepCopy()
+		secret.Namespace = namespace
+		secrets := clientSet.CoreV1().Secrets(namespace)
+		existing, err := secrets.Get(context.Background(), secret.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		exists := err == nil
+		if dryRun || (exists && !overwrite) {
+			return exists, nil
+		}
+		forgetDetectedStorageTypes(namespace)
+		if exists {
+			secret.ResourceVersion = existing.ResourceVersion
+			_, err = secrets.Update(context.Background(), secret, metav1.UpdateOptions{})
+		} else {
+			_, err = secrets.Create(context.Background(), secret, metav1.CreateOptions{})
+		}
+		return exists, err
+	case *corev1.ConfigMap:
+		configMap := item.DeepCopy()
+		configMap.Namespace = namespace
+		configMaps := clientSet.CoreV1().ConfigMaps(namespace)
+		existing, err := configMaps.Get(context.Background(), configMap.Name, metav1.GetOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return false, err
+		}
+		exists := err == nil
+		if dryRun || (exists && !overwrite) {
+			return exists, nil
+		}
+		forgetDetectedStorageTypes(namespace)
+		if exists {
+			configMap.ResourceVersion = existing.ResourceVersion
+			_, err = configMaps.Update(context.Background(), configMap, metav1.UpdateOptions{})
+		} else {
+			_, err = configMaps.Create(context.Background(), configMap, metav1.CreateOptions{})
+		}
+		return exists, err
+	}
+	return false, fmt.Errorf("storage object of type %T cannot be restored", object)
 }
@@ -0,0 +1,283 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	utils "github.com/maorfr/helm-plugin-utils/pkg"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
+)
+
+// Helm v2 storage types
+const (
+	StorageConfigMaps = "configmaps"
+	StorageSecrets    = "secrets"
+	// StorageAuto detects the storage type from the storage objects found
+	StorageAuto = "auto"
+	// StorageBoth merges the release versions stored in ConfigMaps and in Secrets
+	StorageBoth = "both"
+)
+
+var (
+	// clientSet is the Kubernetes client set used instead of the one of the kube config when set
+	clientSet kubernetes.Interface
+	// storageOverride is the storage used instead of the Tiller storage when set
+	storageOverride Storage
+	// detectedStorageTypes are the storage types detected per Tiller namespace and label,
+	// so that the storage is probed once until the storage objects of the namespace are written
+	detectedStorageTypes = map[string]string{}
+)
+
+// Storage is a Helm v2 release storage backend of a Tiller namespace. Each release version is
+// stored in an object named after the release version, with the Tiller labels like OWNER, NAME,
+// STATUS and VERSION.
+type Storage interface {
+	// Type returns the storage type, 'configmaps', 'secrets' or 'both'
+	Type() string
+	// List returns the storage objects which match a label selector, for example OWNER=TILLER
+	List(selector string) ([]runtime.Object, error)
+	// Get returns a storage object by name. The error is a Kubernetes NotFound error if
+	// the object does not exist.
+	Get(name string) (runtime.Object, error)
+	// Delete deletes a storage object by name
+	Delete(name string) error
+}
+
+// SetClientSet sets the Kubernetes client set used for Helm v2 storage and Tiller instead of the
+// one of the kube config, for example a fake client set. Setting nil restores the kube config.
+func SetClientSet(cs kubernetes.Interface) {
+	clientSet = cs
+}
+
+// SetStorage sets the storage used instead of the Tiller storage of any namespace, for example
+// an in-memory storage. Setting nil restores the Tiller storage.
+func SetStorage(storage Storage) {
+	storageOverride = storage
+}
+
+// ValidateStorageType returns an error if a storage type cannot be used to read Helm v2 storage
+func ValidateStorageType(storageType string) error {
+	switch storageType {
+	case StorageConfigMaps, StorageSecrets, StorageAuto, StorageBoth:
+		return nil
+	}
+	return fmt.Errorf("Helm v2 release storage \"%s\" is not supported, it needs to be '%s', '%s', '%s' or '%s'", storageType, StorageConfigMaps, StorageSecrets, StorageAuto, StorageBoth)
+}
+
+// NewStorage returns the storage of a type, 'configmaps', 'secrets' or 'both', in a Tiller namespace
+func NewStorage(storageType string, cs kubernetes.Interface, namespace string) (Storage, error) {
+	switch storageType {
+	case StorageConfigMaps:
+		return NewConfigMapStorage(cs, namespace), nil
+	case StorageSecrets:
+		return NewSecretStorage(cs, namespace), nil
+	case StorageBoth:
+		return NewMergedStorage(cs, namespace), nil
+	}
+	return nil, fmt.Errorf("Helm v2 storage type \"%s\" is not supported, it needs to be '%s', '%s' or '%s'", storageType, StorageConfigMaps, StorageSecrets, StorageBoth)
+}
+
+// GetStorage returns the Helm v2 storage of the Tiller namespace as per the retrieve options.
+// The storage type is detected from the Tiller deployment unless Tiller is running out of the cluster.
+func GetStorage(retOpts RetrieveOptions, kubeConfig common.KubeConfig) (Storage, error) {
+	if storageOverride != nil {
+		return storageOverride, nil
+	}
+	if retOpts.TillerNamespace == "" {
+		retOpts.TillerNamespace = "kube-system"
+	}
+	storageType, err := GetStorageType(retOpts, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	return NewStorage(storageType, GetClientSet(kubeConfig), retOpts.TillerNamespace)
+}
+
+// GetClientSet returns the Kubernetes client set set with SetClientSet, or the one of the kube config
+func GetClientSet(kubeConfig common.KubeConfig) kubernetes.Interface {
+	if clientSet != nil {
+		return clientSet
+	}
+	return utils.GetClientSetWithKubeConfig(kubeConfig.File, kubeConfig.Context)
+}
+
+// getTillerStorage returns the storage type set on the Tiller pod of a namespace. It is 'configmaps'
+// unless Tiller is run with the secret storage.
+func getTillerStorage(cs kubernetes.Interface, tillerNamespace string) (string, error) {
+	pods, err := cs.CoreV1().Pods(tillerNamespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: "name=tiller",
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(pods.Items) == 0 || len(pods.Items[0].Spec.Containers) == 0 {
+		return "", fmt.Errorf("Found 0 tiller pods in namespace \"%s\", the release storage can be set to 'auto' to detect it from the release versions", tillerNamespace)
+	}
+
+	return getContainerStorage(pods.Items[0].Spec.Containers[0]), nil
+}
+
+// getContainerStorage returns the storage type set on a Tiller container
+func getContainerStorage(container corev1.Container) string {
+	for _, c := range container.Command {
+		if strings.Contains(c, "secret") {
+			return StorageSecrets
+		}
+	}
+	for _, a := range container.Args {
+		if strings.Contains(a, "storage=secret") {
+			return StorageSecrets
+		}
+	}
+	return StorageConfigMaps
+}
+
+// detectStorageType returns the storage type of the release versions found with the Tiller label in
+// the ConfigMaps and Secrets of a Tiller namespace. It defaults to 'configmaps', the Tiller default, if
+// none are found and returns an error if release versions are found in both.
+func detectStorageType(cs kubernetes.Interface, tillerNamespace, tillerLabel string) (string, error) {
+	key := tillerNamespace + "/" + tillerLabel
+	if storageType, ok := detectedStorageTypes[key]; ok {
+		return storageType, nil
+	}
+
+	configMaps, err := NewConfigMapStorage(cs, tillerNamespace).List(tillerLabel)
+	if err != nil {
+		return "", fmt.Errorf("[Helm 2] Failed to probe ConfigMaps in namespace \"%s\" due to the following error: %s", tillerNamespace, err)
+	}
+	secrets, err := NewSecretStorage(cs, tillerNamespace).List(tillerLabel)
+	if err != nil {
+		return "", fmt.Errorf("[Helm 2] Failed to probe Secrets in namespace \"%s\" due to the following error: %s", tillerNamespace, err)
+	}
+	logger.Infof("[Helm 2] %d release versions found in ConfigMaps and %d in Secrets in namespace \"%s\" with label \"%s\".\n", len(configMaps), len(secrets), tillerNamespace, tillerLabel)
+
+	var storageType string
+	switch {
+	case len(configMaps) > 0 && len(secrets) > 0:
+		return "", fmt.Errorf("[Helm 2] release versions exist in both ConfigMaps and Secrets in namespace \"%s\", the release storage needs to be set to 'configmaps', 'secrets' or 'both'", tillerNamespace)
+	case len(secrets) > 0:
+		storageType = StorageSecrets
+	default:
+		storageType = StorageConfigMaps
+	}
+	logger.Infof("[Helm 2] Release storage \"%s\" detected in namespace \"%s\".\n", storageType, tillerNamespace)
+	detectedStorageTypes[key] = storageType
+	return storageType, nil
+}
+
+// forgetDetectedStorageTypes clears the storage types detected in a Tiller namespace, as writing
+// the storage objects of the namespace can change the storage type detected
+func forgetDetectedStorageTypes(namespace string) {
+	for key := range detectedStorageTypes {
+		if strings.HasPrefix(key, namespace+"/") {
+			delete(detectedStorageTypes, key)
+		}
+	}
+}
+
+// ConfigMapStorage is the Helm v2 storage in ConfigMaps, which is the Tiller default
+type ConfigMapStorage struct {
+	clientSet kubernetes.Interface
+	namespace string
+}
+
+// NewConfigMapStorage returns the storage in the ConfigMaps of a Tiller namespace
+func NewConfigMapStorage(cs kubernetes.Interface, namespace string) *ConfigMapStorage {
+	return &ConfigMapStorage{clientSet: cs, namespace: namespace}
+}
+
+// Type returns 'configmaps'
+func (storage *ConfigMapStorage) Type() string {
+	return StorageConfigMaps
+}
+
+// List returns the ConfigMaps which match a label selector
+func (storage *ConfigMapStorage) List(selector string) ([]runtime.Object, error) {
+	configMaps, err := storage.clientSet.CoreV1().ConfigMaps(storage.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	for i := range configMaps.Items {
+		objects = append(objects, &configMaps.Items[i])
+	}
+	return objects, nil
+}
+
+// Get returns a ConfigMap by name
+func (storage *ConfigMapStorage) Get(name string) (runtime.Object, error) {
+	return storage.clientSet.CoreV1().ConfigMaps(storage.namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// Delete deletes a ConfigMap by name
+func (storage *ConfigMapStorage) Delete(name string) error {
+	forgetDetectedStorageTypes(storage.namespace)
+	return storage.clientSet.CoreV1().ConfigMaps(storage.namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
+
+// SecretStorage is the Helm v2 storage in Secrets, used when Tiller is run with --storage=secret
+type SecretStorage struct {
+	clientSet kubernetes.Interface
+	namespace string
+}
+
+// NewSecretStorage returns the storage in the Secrets of a Tiller namespace
+func NewSecretStorage(cs kubernetes.Interface, namespace string) *SecretStorage {
+	return &SecretStorage{clientSet: cs, namespace: namespace}
+}
+
+// Type returns 'secrets'
+func (storage *SecretStorage) Type() string {
+	return StorageSecrets
+}
+
+// List returns the Secrets which match a label selector
+func (storage *SecretStorage) List(selector string) ([]runtime.Object, error) {
+	secrets, err := storage.clientSet.CoreV1().Secrets(storage.namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: selector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var objects []runtime.Object
+	for i := range secrets.Items {
+		objects = append(objects, &secrets.Items[i])
+	}
+	return objects, nil
+}
+
+// Get returns a Secret by name
+func (storage *SecretStorage) Get(name string) (runtime.Object, error) {
+	return storage.clientSet.CoreV1().Secrets(storage.namespace).Get(context.Background(), name, metav1.GetOptions{})
+}
+
+// Delete deletes a Secret by name
+func (storage *SecretStorage) Delete(name string) error {
+	forgetDetectedStorageTypes(storage.namespace)
+	return storage.clientSet.CoreV1().Secrets(storage.namespace).Delete(context.Background(), name, metav1.DeleteOptions{})
+}
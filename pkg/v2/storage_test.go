@@ -0,0 +1,67 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+)
+
+func TestValidateStorageType(t *testing.T) {
+	for _, storageType := range []string{StorageConfigMaps, StorageSecrets, StorageAuto, StorageBoth} {
+		if err := ValidateStorageType(storageType); err != nil {
+			t.Errorf("ValidateStorageType(%q) failed: %s", storageType, err)
+		}
+	}
+	for _, storageType := range []string{"", "configmap", "memory"} {
+		if err := ValidateStorageType(storageType); err == nil {
+			t.Errorf("ValidateStorageType(%q) succeeded, want error", storageType)
+		}
+	}
+}
+
+func TestDetectStorageTypeAfterWrites(t *testing.T) {
+	labels := map[string]string{"OWNER": "TILLER", "NAME": "app"}
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "app.v1", Namespace: "kube-system", Labels: labels}}
+	cs := fake.NewSimpleClientset(secret)
+
+	storageType, err := detectStorageType(cs, "kube-system", "OWNER=TILLER")
+	if err != nil || storageType != StorageSecrets {
+		t.Fatalf("detectStorageType() = %q, %v, want %q", storageType, err, StorageSecrets)
+	}
+
+	// The release version is moved from a Secret to a ConfigMap, as by cleanup and restore
+	if err := NewSecretStorage(cs, "kube-system").Delete("app.v1"); err != nil {
+		t.Fatal(err)
+	}
+	SetClientSet(cs)
+	defer SetClientSet(nil)
+	configMap := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "app.v1", Labels: labels}}
+	if _, err := RestoreStorageObject("kube-system", configMap, false, false, common.KubeConfig{}); err != nil {
+		t.Fatal(err)
+	}
+
+	storageType, err = detectStorageType(cs, "kube-system", "OWNER=TILLER")
+	if err != nil || storageType != StorageConfigMaps {
+		t.Errorf("detectStorageType() after writes = %q, %v, want %q", storageType, err, StorageConfigMaps)
+	}
+}
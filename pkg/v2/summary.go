@@ -0,0 +1,59 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v2
+
+import (
+	"fmt"
+	"time"
+
+	rls "k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// ReleaseSummary describes a Helm v2 release from its latest version
+type ReleaseSummary struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	// Chart is the chart name and version, for example nginx-1.2.3
+	Chart        string    `json:"chart"`
+	AppVersion   string    `json:"appVersion"`
+	Status       string    `json:"status"`
+	Version      int32     `json:"version"`
+	Versions     int       `json:"versions"`
+	LastDeployed time.Time `json:"lastDeployed"`
+}
+
+// SummarizeRelease returns the summary of a release from its versions, sorted by version
+// as returned by GetReleaseVersions
+func SummarizeRelease(versions []*rls.Release) ReleaseSummary {
+	if len(versions) <= 0 {
+		return ReleaseSummary{}
+	}
+	latest := versions[len(versions)-1]
+	summary := ReleaseSummary{
+		Name:         latest.Name,
+		Namespace:    latest.Namespace,
+		Status:       GetStatus(latest),
+		Version:      latest.Version,
+		Versions:     len(versions),
+		LastDeployed: getLastDeployed(latest),
+	}
+	if latest.Chart != nil && latest.Chart.Metadata != nil {
+		summary.Chart = fmt.Sprintf("%s-%s", latest.Chart.Metadata.Name, latest.Chart.Metadata.Version)
+		summary.AppVersion = latest.Chart.Metadata.AppVersion
+	}
+	return summary
+}
@@ -18,13 +18,14 @@ package v2
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"strings"
 
 	utils "github.com/maorfr/helm-plugin-utils/pkg"
 	"github.com/mitchellh/go-homedir"
+
+	"github.com/helm/helm-2to3/pkg/logger"
 )
 
 const sep = string(filepath.Separator)
@@ -32,36 +33,42 @@ const sep = string(filepath.Separator)
 // RemoveHomeFolder removes the v2 Helm home folder
 func RemoveHomeFolder(dryRun bool) error {
 	homeDir := HomeDir()
-	log.Printf("[Helm 2] Home folder \"%s\" will be deleted.\n", homeDir)
+	homeLog := logger.With(logger.Fields{logger.FieldOperation: "remove-home"})
+	homeLog.Infof("[Helm 2] Home folder \"%s\" will be deleted.\n", homeDir)
 	if !dryRun {
 		if err := os.RemoveAll(homeDir); err != nil {
 			return fmt.Errorf("[Helm 2] Failed to delete \"%s\" due to the following error: %s.\n", homeDir, err)
 		}
-		log.Printf("[Helm 2] Home folder \"%s\" deleted.\n", homeDir)
+		homeLog.Infof("[Helm 2] Home folder \"%s\" deleted.\n", homeDir)
 	}
 	return nil
 
 }
 
-// RemoveTiller removes Tiller service in a particular namespace from the cluster
-func RemoveTiller(tillerNamespace string, dryRun bool) error {
+// RemoveTiller removes the Tiller Deployment and Service of a particular namespace from the cluster.
+// The Service is not removed if its name is empty, as for a Tiller without Service.
+func RemoveTiller(tillerNamespace, deployment, service string, dryRun bool) error {
 	if tillerNamespace == "" {
 		tillerNamespace = "kube-system"
 	}
+	tillerLog := logger.With(logger.Fields{logger.FieldOperation: "remove-tiller", logger.FieldNamespace: tillerNamespace})
 	if !dryRun {
-		log.Printf("[Helm 2] Tiller \"%s\" in \"%s\" namespace will be removed.\n", "deploy", tillerNamespace)
-		err := executeKubsDeleteTillerCmd(tillerNamespace, "deploy")
+		tillerLog.Infof("[Helm 2] Tiller \"%s\" \"%s\" in \"%s\" namespace will be removed.\n", "deploy", deployment, tillerNamespace)
+		err := executeKubsDeleteTillerCmd(tillerNamespace, "deploy", deployment)
 		if err != nil {
 			return err
 		}
-		log.Printf("[Helm 2] Tiller \"%s\" in \"%s\" namespace was removed successfully.\n", "deploy", tillerNamespace)
+		tillerLog.Infof("[Helm 2] Tiller \"%s\" \"%s\" in \"%s\" namespace was removed successfully.\n", "deploy", deployment, tillerNamespace)
 
-		log.Printf("[Helm 2] Tiller \"%s\" in \"%s\" namespace will be removed.\n", "service", tillerNamespace)
-		err = executeKubsDeleteTillerCmd(tillerNamespace, "service")
+		if service == "" {
+			return nil
+		}
+		tillerLog.Infof("[Helm 2] Tiller \"%s\" \"%s\" in \"%s\" namespace will be removed.\n", "service", service, tillerNamespace)
+		err = executeKubsDeleteTillerCmd(tillerNamespace, "service", service)
 		if err != nil {
 			return err
 		}
-		log.Printf("[Helm 2] Tiller \"%s\" in \"%s\" namespace was removed successfully.\n", "service", tillerNamespace)
+		tillerLog.Infof("[Helm 2] Tiller \"%s\" \"%s\" in \"%s\" namespace was removed successfully.\n", "service", service, tillerNamespace)
 	}
 	return nil
 }
@@ -82,11 +89,11 @@ func GetReleaseVersionName(releaseName string, releaseVersion int32) string {
 	return fmt.Sprintf("%s.v%d", releaseName, releaseVersion)
 }
 
-func executeKubsDeleteTillerCmd(tillerNamespace, label string) error {
-	delLabel := label + "/tiller-deploy"
+func executeKubsDeleteTillerCmd(tillerNamespace, label, name string) error {
+	delLabel := label + "/" + name
 	applyCmd := []string{"kubectl", "delete", "--namespace", tillerNamespace, delLabel}
 	output := utils.Execute(applyCmd)
-	if !strings.Contains(string(output), "\"tiller-deploy\" deleted") {
+	if !strings.Contains(string(output), "\""+name+"\" deleted") {
 		return fmt.Errorf("[Helm 2] Failed to remove Tiller \"%s\" in \"%s\" namespace due to the following error: %s", label, tillerNamespace, string(output))
 	}
 	return nil
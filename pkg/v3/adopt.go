@@ -0,0 +1,137 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
+
+	"helm.sh/helm/v3/pkg/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+)
+
+// Helm v3 ownership metadata of release resources
+const (
+	managedByLabel             = "app.kubernetes.io/managed-by"
+	managedByValue             = "Helm"
+	releaseNameAnnotation      = "meta.helm.sh/release-name"
+	releaseNamespaceAnnotation = "meta.helm.sh/release-namespace"
+)
+
+// Outcomes of adopting a resource
+const (
+	AdoptAdopted = "adopted"
+	AdoptMissing = "missing"
+	AdoptOwned   = "owned by another release"
+)
+
+// AdoptResult is the outcome of adopting a resource of a release
+type AdoptResult struct {
+	Kind      string
+	Name      string
+	Namespace string
+	Outcome   string
+	// Owner is the release owning the resource, when owned by another release
+	Owner string
+}
+
+// AdoptResources sets the Helm v3 ownership label and annotations on the live resources of the
+// manifest of a release version, so that Helm v3 can upgrade the release. Resources which are
+// missing or owned by another release are not changed and are reported in the results.
+func AdoptResources(rel *release.Release, dryRun bool, kubeConfig common.KubeConfig) ([]AdoptResult, error) {
+	cfg, err := GetActionConfig(rel.Namespace, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+	resources, err := cfg.KubeClient.Build(bytes.NewBufferString(rel.Manifest), false)
+	if err != nil {
+		return nil, err
+	}
+	restConfig, err := settings.RESTClientGetter().ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(restConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"labels": map[string]string{
+				managedByLabel: managedByValue,
+			},
+			"annotations": map[string]string{
+				releaseNameAnnotation:      rel.Name,
+				releaseNamespaceAnnotation: rel.Namespace,
+			},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := []AdoptResult{}
+	for _, info := range resources {
+		result := AdoptResult{
+			Kind:      info.Mapping.GroupVersionKind.Kind,
+			Name:      info.Name,
+			Namespace: info.Namespace,
+		}
+		var resourceClient dynamic.ResourceInterface = client.Resource(info.Mapping.Resource)
+		if info.Namespaced() {
+			resourceClient = client.Resource(info.Mapping.Resource).Namespace(info.Namespace)
+		}
+
+		live, err := resourceClient.Get(context.Background(), info.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			result.Outcome = AdoptMissing
+			results = append(results, result)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		annotations := live.GetAnnotations()
+		ownerName := annotations[releaseNameAnnotation]
+		ownerNamespace := annotations[releaseNamespaceAnnotation]
+		if (ownerName != "" && ownerName != rel.Name) || (ownerNamespace != "" && ownerNamespace != rel.Namespace) {
+			result.Outcome = AdoptOwned
+			result.Owner = ownerNamespace + "/" + ownerName
+			results = append(results, result)
+			continue
+		}
+
+		if !dryRun {
+			_, err = resourceClient.Patch(context.Background(), info.Name, types.MergePatchType, patch, metav1.PatchOptions{})
+			if err != nil {
+				return nil, err
+			}
+		}
+		result.Outcome = AdoptAdopted
+		results = append(results, result)
+	}
+	return results, nil
+}
@@ -0,0 +1,128 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+	"helm.sh/helm/v3/pkg/release"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// APIMapping maps a deprecated Kubernetes API version of a kind to its replacement.
+// The mapping applies to all kinds of the deprecated API version when the kind is not set.
+type APIMapping struct {
+	Kind                 string `json:"kind,omitempty"`
+	DeprecatedAPIVersion string `json:"deprecatedAPIVersion"`
+	NewAPIVersion        string `json:"newAPIVersion"`
+}
+
+// APIMappingFile is the content of an API mapping file
+type APIMappingFile struct {
+	Mappings []APIMapping `json:"mappings"`
+}
+
+// APISubstitution is an API version replaced in an object of a release version
+type APISubstitution struct {
+	// Source is 'manifest' or the hook path
+	Source string
+	Kind   string
+	Name   string
+	From   string
+	To     string
+}
+
+// LoadAPIMappings reads the deprecated API mappings from a file
+func LoadAPIMappings(fileName string) ([]APIMapping, error) {
+	data, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read API mapping file \"%s\" due to the following error: %s", fileName, err)
+	}
+	var mappingFile APIMappingFile
+	if err := sigsyaml.UnmarshalStrict(data, &mappingFile); err != nil {
+		return nil, fmt.Errorf("Failed to parse API mapping file \"%s\" due to the following error: %s", fileName, err)
+	}
+	for i, mapping := range mappingFile.Mappings {
+		if mapping.DeprecatedAPIVersion == "" || mapping.NewAPIVersion == "" {
+			return nil, fmt.Errorf("API mapping %d of file \"%s\" needs deprecatedAPIVersion and newAPIVersion", i+1, fileName)
+		}
+	}
+	return mappingFile.Mappings, nil
+}
+
+// MapDeprecatedAPIs replaces the deprecated API versions in the manifest and hook manifests
+// of a release version. It returns the substitutions made. An error is returned if an API version
+// cannot be replaced, in which case the release version is not changed.
+func MapDeprecatedAPIs(rel *release.Release, mappings []APIMapping) ([]APISubstitution, error) {
+	if len(mappings) == 0 {
+		return nil, nil
+	}
+	substitutions := []APISubstitution{}
+	manifest, subs, err := mapManifestAPIs(rel.Manifest, "manifest", mappings)
+	if err != nil {
+		return nil, err
+	}
+	substitutions = append(substitutions, subs...)
+	hookManifests := make([]string, len(rel.Hooks))
+	for i, hook := range rel.Hooks {
+		hookManifests[i], subs, err = mapManifestAPIs(hook.Manifest, hook.Path, mappings)
+		if err != nil {
+			return nil, err
+		}
+		substitutions = append(substitutions, subs...)
+	}
+	rel.Manifest = manifest
+	for i, hook := range rel.Hooks {
+		hook.Manifest = hookManifests[i]
+	}
+	return substitutions, nil
+}
+
+func mapManifestAPIs(manifest, source string, mappings []APIMapping) (string, []APISubstitution, error) {
+	substitutions := []APISubstitution{}
+	mapped, err := editManifest(manifest, func(doc *yaml.Node) []scalarEdit {
+		apiVersion := mappingValue(doc, "apiVersion")
+		kind := mappingValue(doc, "kind")
+		if apiVersion == nil || kind == nil || apiVersion.Kind != yaml.ScalarNode {
+			return nil
+		}
+		for _, mapping := range mappings {
+			if mapping.DeprecatedAPIVersion != apiVersion.Value || (mapping.Kind != "" && mapping.Kind != kind.Value) {
+				continue
+			}
+			var name string
+			if nameNode := mappingValue(mappingValue(doc, "metadata"), "name"); nameNode != nil {
+				name = nameNode.Value
+			}
+			substitutions = append(substitutions, APISubstitution{
+				Source: source,
+				Kind:   kind.Value,
+				Name:   name,
+				From:   apiVersion.Value,
+				To:     mapping.NewAPIVersion,
+			})
+			return []scalarEdit{{node: apiVersion, value: mapping.NewAPIVersion}}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("Failed to map the API versions of %s due to the following error: %s", source, err)
+	}
+	return mapped, substitutions, nil
+}
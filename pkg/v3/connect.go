@@ -17,20 +17,64 @@ limitations under the License.
 package v3
 
 import (
+	"errors"
 	"fmt"
-	"log"
 	"os"
 
 	"helm.sh/helm/v3/pkg/action"
 	"helm.sh/helm/v3/pkg/cli"
+	"helm.sh/helm/v3/pkg/kube"
+	"helm.sh/helm/v3/pkg/storage"
+	"helm.sh/helm/v3/pkg/storage/driver"
+	"k8s.io/client-go/kubernetes"
 
 	common "github.com/helm/helm-2to3/pkg/common"
+	"github.com/helm/helm-2to3/pkg/logger"
+)
+
+// Helm v3 storage drivers
+const (
+	DriverConfigMap = "configmap"
+	DriverSecret    = "secret"
+	DriverSQL       = "sql"
 )
 
 var (
 	settings = cli.New()
+
+	storageDriver = os.Getenv("HELM_DRIVER")
+	sqlConnection = os.Getenv("HELM_DRIVER_SQL_CONNECTION_STRING")
+	// SQL drivers per namespace, so that the database is connected to once. Helm does not expose the
+	// database of a SQL driver, so the connections are closed when the process exits.
+	sqlDrivers = map[string]*driver.SQL{}
+	// clientSet is the Kubernetes client set of the storage drivers instead of the one of the kube config when set
+	clientSet kubernetes.Interface
 )
 
+// SetStorageDriver sets the Helm v3 storage driver used instead of the one set by the
+// HELM_DRIVER environment variable. The connection string is only used by the SQL driver.
+func SetStorageDriver(driverName, connection string) error {
+	switch driverName {
+	case "secret", "secrets", "configmap", "configmaps":
+	case DriverSQL:
+		if connection == "" {
+			return errors.New("a connection string is needed by the Helm v3 SQL storage driver")
+		}
+	default:
+		return fmt.Errorf("Helm v3 storage driver \"%s\" is not supported, it needs to be '%s', '%s' or '%s'", driverName, DriverSecret, DriverConfigMap, DriverSQL)
+	}
+	storageDriver = driverName
+	sqlConnection = connection
+	return nil
+}
+
+// SetClientSet sets the Kubernetes client set used by the Helm v3 secret and configmap storage
+// drivers instead of the one of the kube config, for example a fake client set. Setting nil
+// restores the kube config.
+func SetClientSet(cs kubernetes.Interface) {
+	clientSet = cs
+}
+
 // GetActionConfig returns action configuration based on Helm env
 func GetActionConfig(namespace string, kubeConfig common.KubeConfig) (*action.Configuration, error) {
 	actionConfig := new(action.Configuration)
@@ -38,18 +82,60 @@ func GetActionConfig(namespace string, kubeConfig common.KubeConfig) (*action.Co
 	// Add kube config settings passed by user
 	settings.KubeConfig = kubeConfig.File
 	settings.KubeContext = kubeConfig.Context
+	settings.Debug = logger.Default().IsDebug()
 
-	err := actionConfig.Init(settings.RESTClientGetter(), namespace, os.Getenv("HELM_DRIVER"), debug)
+	helmDriver := storageDriver
+	if helmDriver == DriverSQL {
+		// The SQL storage is set below, as action config panics if the database cannot be connected to
+		helmDriver = DriverSecret
+	}
+	err := actionConfig.Init(settings.RESTClientGetter(), namespace, helmDriver, debug)
 	if err != nil {
 		return nil, err
 	}
+	if storageDriver == DriverSQL {
+		sqlDriver, ok := sqlDrivers[namespace]
+		if !ok {
+			sqlDriver, err = driver.NewSQL(sqlConnection, debug, namespace)
+			if err != nil {
+				return nil, fmt.Errorf("Failed to connect to the Helm v3 SQL storage due to the following error: %s", err)
+			}
+			sqlDrivers[namespace] = sqlDriver
+		}
+		actionConfig.Releases = storage.Init(sqlDriver)
+	} else if clientSet != nil {
+		switch StorageResource() {
+		case "configmaps":
+			configMapsDriver := driver.NewConfigMaps(clientSet.CoreV1().ConfigMaps(namespace))
+			configMapsDriver.Log = debug
+			actionConfig.Releases = storage.Init(configMapsDriver)
+		case "secrets":
+			secretsDriver := driver.NewSecrets(clientSet.CoreV1().Secrets(namespace))
+			secretsDriver.Log = debug
+			actionConfig.Releases = storage.Init(secretsDriver)
+		}
+	}
+
+	// Resources without a namespace in manifests belong to the release namespace
+	if kubeClient, ok := actionConfig.KubeClient.(*kube.Client); ok {
+		kubeClient.Namespace = namespace
+	}
 
 	return actionConfig, err
 }
 
-func debug(format string, v ...interface{}) {
-	if settings.Debug {
-		format = fmt.Sprintf("[debug] %s\n", format)
-		log.Output(2, fmt.Sprintf(format, v...))
+// StorageResource returns the Kubernetes resource used by the Helm v3 storage driver.
+// It returns an empty string if the driver does not store releases in Kubernetes.
+func StorageResource() string {
+	switch storageDriver {
+	case "", "secret", "secrets":
+		return "secrets"
+	case "configmap", "configmaps":
+		return "configmaps"
 	}
+	return ""
+}
+
+func debug(format string, v ...interface{}) {
+	logger.Debugf("[debug] "+format, v...)
 }
@@ -0,0 +1,71 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"testing"
+)
+
+func restoreStorageDriver(t *testing.T) {
+	driverName, connection := storageDriver, sqlConnection
+	t.Cleanup(func() {
+		storageDriver, sqlConnection = driverName, connection
+	})
+}
+
+func TestSetStorageDriver(t *testing.T) {
+	tests := []struct {
+		driver     string
+		connection string
+		wantErr    bool
+		resource   string
+	}{
+		{driver: "secret", resource: "secrets"},
+		{driver: "secrets", resource: "secrets"},
+		{driver: "configmap", resource: "configmaps"},
+		{driver: "configmaps", connection: "ignored", resource: "configmaps"},
+		{driver: "sql", connection: "host=localhost dbname=helm", resource: ""},
+		{driver: "sql", wantErr: true},
+		{driver: "memory", wantErr: true},
+		{driver: "", wantErr: true},
+	}
+	restoreStorageDriver(t)
+	for _, tt := range tests {
+		storageDriver, sqlConnection = DriverSecret, ""
+
+		err := SetStorageDriver(tt.driver, tt.connection)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("SetStorageDriver(%q, %q) succeeded, want error", tt.driver, tt.connection)
+			}
+			if storageDriver != DriverSecret || sqlConnection != "" {
+				t.Errorf("SetStorageDriver(%q, %q) changed the driver to %q and connection to %q on error", tt.driver, tt.connection, storageDriver, sqlConnection)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("SetStorageDriver(%q, %q) failed: %s", tt.driver, tt.connection, err)
+			continue
+		}
+		if storageDriver != tt.driver || sqlConnection != tt.connection {
+			t.Errorf("SetStorageDriver(%q, %q) set driver %q and connection %q", tt.driver, tt.connection, storageDriver, sqlConnection)
+		}
+		if resource := StorageResource(); resource != tt.resource {
+			t.Errorf("StorageResource() with driver %q = %q, want %q", tt.driver, resource, tt.resource)
+		}
+	}
+}
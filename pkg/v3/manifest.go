@@ -0,0 +1,165 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var docSeparator = regexp.MustCompile(`(?m)^---[ \t]*$`)
+
+// scalarEdit replaces the value of a scalar node of a manifest document
+type scalarEdit struct {
+	node  *yaml.Node
+	value string
+}
+
+// editManifest applies edits to the scalar values of each document of a manifest. The edits
+// are made in place in the manifest text so that the formatting and comments are preserved.
+// Documents which are not valid YAML are left untouched. An error is returned if a scalar to
+// edit cannot be replaced in place, like a multi-line scalar.
+func editManifest(manifest string, edit func(doc *yaml.Node) []scalarEdit) (string, error) {
+	separators := docSeparator.FindAllStringIndex(manifest, -1)
+	var b strings.Builder
+	start := 0
+	for _, separator := range separators {
+		doc, err := editDocument(manifest[start:separator[0]], edit)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(doc)
+		b.WriteString(manifest[separator[0]:separator[1]])
+		start = separator[1]
+	}
+	doc, err := editDocument(manifest[start:], edit)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(doc)
+	return b.String(), nil
+}
+
+func editDocument(doc string, edit func(doc *yaml.Node) []scalarEdit) (string, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(doc), &root); err != nil {
+		return doc, nil
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return doc, nil
+	}
+	edits := edit(root.Content[0])
+	if len(edits) == 0 {
+		return doc, nil
+	}
+
+	lines := strings.SplitAfter(doc, "\n")
+	// Apply the edits from the end of the document so that positions stay valid
+	sort.Slice(edits, func(i, j int) bool {
+		if edits[i].node.Line != edits[j].node.Line {
+			return edits[i].node.Line > edits[j].node.Line
+		}
+		return edits[i].node.Column > edits[j].node.Column
+	})
+	for _, e := range edits {
+		if e.node.Line < 1 || e.node.Line > len(lines) {
+			return "", fmt.Errorf("value \"%s\" at line %d cannot be edited as it is out of the document", e.node.Value, e.node.Line)
+		}
+		line := []rune(lines[e.node.Line-1])
+		begin := e.node.Column - 1
+		end, err := scalarEnd(line, begin, e.node)
+		if err != nil {
+			return "", err
+		}
+		lines[e.node.Line-1] = string(line[:begin]) + quoteScalar(e.value, e.node.Style) + string(line[end:])
+	}
+	return strings.Join(lines, ""), nil
+}
+
+// scalarEnd returns the position after a scalar node which begins at a position of a line. An
+// error is returned if the scalar does not end on the same line or its text is not its value.
+func scalarEnd(line []rune, begin int, node *yaml.Node) (int, error) {
+	notEditable := fmt.Errorf("value \"%s\" at line %d, column %d cannot be edited in place", node.Value, node.Line, node.Column)
+	if begin < 0 || begin >= len(line) || node.Kind != yaml.ScalarNode {
+		return 0, notEditable
+	}
+	switch node.Style {
+	case yaml.DoubleQuotedStyle:
+		if line[begin] != '"' {
+			return 0, notEditable
+		}
+		for i := begin + 1; i < len(line); i++ {
+			switch line[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1, nil
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		if line[begin] != '\'' {
+			return 0, notEditable
+		}
+		for i := begin + 1; i < len(line); i++ {
+			if line[i] != '\'' {
+				continue
+			}
+			// A quote is escaped by doubling it
+			if i+1 < len(line) && line[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1, nil
+		}
+	case 0:
+		end := begin + len([]rune(node.Value))
+		if end <= len(line) && string(line[begin:end]) == node.Value {
+			return end, nil
+		}
+	}
+	// Literal and folded scalars, and scalars spanning lines
+	return 0, notEditable
+}
+
+// quoteScalar returns the text of a scalar value in a quoting style
+func quoteScalar(value string, style yaml.Style) string {
+	switch style {
+	case yaml.DoubleQuotedStyle:
+		return strconv.Quote(value)
+	case yaml.SingleQuotedStyle:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+	return value
+}
+
+// mappingValue returns the value node of a key in a mapping node
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
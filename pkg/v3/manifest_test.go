@@ -0,0 +1,168 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"reflect"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func TestRewriteNamespace(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "plain",
+			manifest: "kind: ConfigMap\nmetadata:\n  name: app\n  namespace: old # comment\n",
+			want:     "kind: ConfigMap\nmetadata:\n  name: app\n  namespace: new # comment\n",
+		},
+		{
+			name:     "double quoted",
+			manifest: "metadata:\n  namespace: \"old\"\n  name: \"a\\\"b\"\n",
+			want:     "metadata:\n  namespace: \"new\"\n  name: \"a\\\"b\"\n",
+		},
+		{
+			name:     "double quoted with escape",
+			manifest: "metadata: {namespace: \"o\\x6cd\", name: app}\n",
+			want:     "metadata: {namespace: \"new\", name: app}\n",
+		},
+		{
+			name:     "single quoted with doubled quote",
+			manifest: "metadata:\n  name: 'it''s'\n  namespace: 'old' # 'comment'\n",
+			want:     "metadata:\n  name: 'it''s'\n  namespace: 'new' # 'comment'\n",
+		},
+		{
+			name:     "flow mapping",
+			manifest: "metadata: {name: app, namespace: old}\n",
+			want:     "metadata: {name: app, namespace: new}\n",
+		},
+		{
+			name:     "other namespace",
+			manifest: "metadata:\n  namespace: other\n",
+			want:     "metadata:\n  namespace: other\n",
+		},
+		{
+			name:     "multiple documents",
+			manifest: "---\nmetadata:\n  namespace: old\n---\n# empty\n---\nmetadata:\n  namespace: \"old\"\n",
+			want:     "---\nmetadata:\n  namespace: new\n---\n# empty\n---\nmetadata:\n  namespace: \"new\"\n",
+		},
+		{
+			name:     "multi-line scalar",
+			manifest: "metadata:\n  namespace: \"o\\\n    ld\"\n",
+			wantErr:  true,
+		},
+		{
+			name:     "block scalar",
+			manifest: "metadata:\n  namespace: >-\n    old\n",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rewriteNamespace(tt.manifest, "old", "new")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("rewriteNamespace() = %q, want error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("rewriteNamespace() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetNamespace(t *testing.T) {
+	rel := &release.Release{
+		Namespace: "old",
+		Manifest:  "metadata:\n  namespace: old\n",
+		Hooks: []*release.Hook{
+			{Path: "app/templates/job.yaml", Manifest: "metadata:\n  namespace: 'old'\n"},
+		},
+	}
+	if err := SetNamespace(rel, "new", true); err != nil {
+		t.Fatal(err)
+	}
+	if rel.Namespace != "new" || rel.Manifest != "metadata:\n  namespace: new\n" || rel.Hooks[0].Manifest != "metadata:\n  namespace: 'new'\n" {
+		t.Errorf("SetNamespace() moved the release to %q with manifest %q and hook %q", rel.Namespace, rel.Manifest, rel.Hooks[0].Manifest)
+	}
+
+	failing := &release.Release{
+		Namespace: "old",
+		Manifest:  "metadata:\n  namespace: old\n",
+		Hooks: []*release.Hook{
+			{Path: "app/templates/job.yaml", Manifest: "metadata:\n  namespace: >-\n    old\n"},
+		},
+	}
+	if err := SetNamespace(failing, "new", true); err == nil {
+		t.Fatal("SetNamespace() succeeded, want error for the block scalar of the hook")
+	}
+	if failing.Namespace != "old" || failing.Manifest != "metadata:\n  namespace: old\n" {
+		t.Errorf("SetNamespace() changed the release version although it failed")
+	}
+}
+
+func TestMapDeprecatedAPIs(t *testing.T) {
+	mappings := []APIMapping{
+		{Kind: "Deployment", DeprecatedAPIVersion: "extensions/v1beta1", NewAPIVersion: "apps/v1"},
+		{DeprecatedAPIVersion: "rbac.authorization.k8s.io/v1beta1", NewAPIVersion: "rbac.authorization.k8s.io/v1"},
+	}
+	rel := &release.Release{
+		Manifest: "---\napiVersion: extensions/v1beta1\nkind: Deployment\nmetadata:\n  name: web\n" +
+			"---\napiVersion: \"extensions/v1beta1\"\nkind: Ingress\nmetadata:\n  name: web\n" +
+			"---\napiVersion: 'rbac.authorization.k8s.io/v1beta1'\nkind: Role\nmetadata:\n  name: web\n",
+		Hooks: []*release.Hook{
+			{Path: "app/templates/hook.yaml", Manifest: "apiVersion: extensions/v1beta1\nkind: Deployment\nmetadata:\n  name: hook\n"},
+		},
+	}
+
+	substitutions, err := MapDeprecatedAPIs(rel, mappings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantManifest := "---\napiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: web\n" +
+		"---\napiVersion: \"extensions/v1beta1\"\nkind: Ingress\nmetadata:\n  name: web\n" +
+		"---\napiVersion: 'rbac.authorization.k8s.io/v1'\nkind: Role\nmetadata:\n  name: web\n"
+	if rel.Manifest != wantManifest {
+		t.Errorf("MapDeprecatedAPIs() mapped the manifest to %q, want %q", rel.Manifest, wantManifest)
+	}
+	if rel.Hooks[0].Manifest != "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: hook\n" {
+		t.Errorf("MapDeprecatedAPIs() mapped the hook to %q", rel.Hooks[0].Manifest)
+	}
+	want := []APISubstitution{
+		{Source: "manifest", Kind: "Deployment", Name: "web", From: "extensions/v1beta1", To: "apps/v1"},
+		{Source: "manifest", Kind: "Role", Name: "web", From: "rbac.authorization.k8s.io/v1beta1", To: "rbac.authorization.k8s.io/v1"},
+		{Source: "app/templates/hook.yaml", Kind: "Deployment", Name: "hook", From: "extensions/v1beta1", To: "apps/v1"},
+	}
+	if !reflect.DeepEqual(substitutions, want) {
+		t.Errorf("MapDeprecatedAPIs() substitutions = %+v, want %+v", substitutions, want)
+	}
+
+	unmappable := &release.Release{Manifest: "apiVersion: >-\n  extensions/v1beta1\nkind: Deployment\n"}
+	if substitutions, err := MapDeprecatedAPIs(unmappable, mappings); err == nil {
+		t.Errorf("MapDeprecatedAPIs() = %+v, want error for the block scalar", substitutions)
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/cli-runtime/pkg/resource"
+
+	"helm.sh/helm/v3/pkg/release"
+
+	common "github.com/helm/helm-2to3/pkg/common"
+)
+
+// SetNamespace moves a release version to another namespace. If rewriteManifest is set, the
+// 'metadata.namespace' of the objects in the manifest and hooks which is set to the original
+// namespace is rewritten to the new namespace. An error is returned if a namespace cannot be
+// rewritten, in which case the release version is not changed.
+func SetNamespace(rel *release.Release, namespace string, rewriteManifest bool) error {
+	if rewriteManifest {
+		manifest, err := rewriteNamespace(rel.Manifest, rel.Namespace, namespace)
+		if err != nil {
+			return fmt.Errorf("Failed to rewrite the namespace of the manifest due to the following error: %s", err)
+		}
+		hookManifests := make([]string, len(rel.Hooks))
+		for i, hook := range rel.Hooks {
+			hookManifests[i], err = rewriteNamespace(hook.Manifest, rel.Namespace, namespace)
+			if err != nil {
+				return fmt.Errorf("Failed to rewrite the namespace of hook \"%s\" due to the following error: %s", hook.Path, err)
+			}
+		}
+		rel.Manifest = manifest
+		for i, hook := range rel.Hooks {
+			hook.Manifest = hookManifests[i]
+		}
+	}
+	rel.Namespace = namespace
+	return nil
+}
+
+// CheckResourcesInNamespace returns an error if any namespaced resource of the manifest does
+// not exist in the namespace in the cluster
+func CheckResourcesInNamespace(manifest, namespace string, kubeConfig common.KubeConfig) error {
+	cfg, err := GetActionConfig(namespace, kubeConfig)
+	if err != nil {
+		return err
+	}
+	resources, err := cfg.KubeClient.Build(bytes.NewBufferString(manifest), false)
+	if err != nil {
+		return err
+	}
+
+	missing := []string{}
+	for _, info := range resources {
+		if !info.Namespaced() {
+			continue
+		}
+		kind := info.Mapping.GroupVersionKind.Kind
+		if info.Namespace != namespace {
+			missing = append(missing, fmt.Sprintf("%s \"%s\" is in namespace \"%s\"", kind, info.Name, info.Namespace))
+			continue
+		}
+		_, err := resource.NewHelper(info.Client, info.Mapping).Get(namespace, info.Name)
+		if apierrors.IsNotFound(err) {
+			missing = append(missing, fmt.Sprintf("%s \"%s\" not found", kind, info.Name))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("resources of the release are not in namespace \"%s\": %s", namespace, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+func rewriteNamespace(manifest, from, to string) (string, error) {
+	return editManifest(manifest, func(doc *yaml.Node) []scalarEdit {
+		namespace := mappingValue(mappingValue(doc, "metadata"), "namespace")
+		if namespace == nil || namespace.Kind != yaml.ScalarNode || namespace.Value != from {
+			return nil
+		}
+		return []scalarEdit{{node: namespace, value: to}}
+	})
+}
@@ -0,0 +1,84 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"helm.sh/helm/v3/pkg/release"
+)
+
+// Encoding of release versions by the Helm v3 secrets storage driver
+const (
+	releaseSecretPrefix = "sh.helm.release.v1"
+	releaseSecretType   = "helm.sh/release.v1"
+	releaseOwner        = "helm"
+)
+
+// ReleaseSecret returns the Secret in which the Helm v3 secrets storage driver stores a release
+// version, so that it can be created in the cluster later on
+func ReleaseSecret(rel *release.Release) (*corev1.Secret, error) {
+	data, err := encodeRelease(rel)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to encode release \"%s\" due to the following error: %s", rel.Name, err)
+	}
+	return &corev1.Secret{
+		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%s.v%d", releaseSecretPrefix, rel.Name, rel.Version),
+			Namespace: rel.Namespace,
+			Labels: map[string]string{
+				"createdAt": strconv.Itoa(int(time.Now().Unix())),
+				"name":      rel.Name,
+				"owner":     releaseOwner,
+				"status":    rel.Info.Status.String(),
+				"version":   strconv.Itoa(rel.Version),
+			},
+		},
+		Type: releaseSecretType,
+		Data: map[string][]byte{"release": []byte(data)},
+	}, nil
+}
+
+// encodeRelease encodes a release as the Helm v3 storage drivers do, as base64 of gzipped JSON
+func encodeRelease(rel *release.Release) (string, error) {
+	b, err := json.Marshal(rel)
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return "", err
+	}
+	if _, err = w.Write(b); err != nil {
+		return "", err
+	}
+	if err = w.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
@@ -0,0 +1,93 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"helm.sh/helm/v3/pkg/storage/driver"
+)
+
+// TestReleaseSecret checks that the Secret of a release version is decoded by the Helm v3 secrets
+// storage driver and is stored as the driver stores it
+func TestReleaseSecret(t *testing.T) {
+	rel := newRelease()
+	rel.Version = 2
+	secret, err := ReleaseSecret(rel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Key of the release version in the Helm v3 storage
+	key := "sh.helm.release.v1.app.v2"
+	clientSet := fake.NewSimpleClientset(secret)
+	secrets := driver.NewSecrets(clientSet.CoreV1().Secrets(rel.Namespace))
+
+	got, err := secrets.Get(key)
+	if err != nil {
+		t.Fatalf("Helm v3 failed to get the release version: %s", err)
+	}
+	// The release versions are compared as JSON, as decoding turns numbers into floats
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(rel)
+	if string(gotJSON) != string(wantJSON) {
+		t.Errorf("Helm v3 decoded release version %s, want %s", gotJSON, wantJSON)
+	}
+
+	found, err := secrets.Query(map[string]string{"name": rel.Name, "owner": "helm", "status": "deployed"})
+	if err != nil {
+		t.Fatalf("Helm v3 failed to query the release version: %s", err)
+	}
+	if len(found) != 1 || found[0].Version != rel.Version {
+		t.Errorf("Helm v3 queried %d release versions, want version %d", len(found), rel.Version)
+	}
+
+	// The Secret created by Helm v3 for the same release version has the same name, type and labels
+	if err := driver.NewSecrets(clientSet.CoreV1().Secrets("helm")).Create(key, rel); err != nil {
+		t.Fatal(err)
+	}
+	helmSecret, err := clientSet.CoreV1().Secrets("helm").Get(context.Background(), key, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret.Type != helmSecret.Type {
+		t.Errorf("Secret type is \"%s\", Helm v3 uses \"%s\"", secret.Type, helmSecret.Type)
+	}
+	if keys, helmKeys := labelKeys(secret.Labels), labelKeys(helmSecret.Labels); !reflect.DeepEqual(keys, helmKeys) {
+		t.Errorf("Secret labels are %v, Helm v3 uses %v", keys, helmKeys)
+	}
+	for _, key := range []string{"name", "owner", "status", "version"} {
+		if secret.Labels[key] != helmSecret.Labels[key] {
+			t.Errorf("Secret label \"%s\" is \"%s\", Helm v3 uses \"%s\"", key, secret.Labels[key], helmSecret.Labels[key])
+		}
+	}
+}
+
+func labelKeys(labels map[string]string) []string {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
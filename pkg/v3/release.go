@@ -17,7 +17,9 @@ limitations under the License.
 package v3
 
 import (
+	"errors"
 	"fmt"
+	"path"
 	"strings"
 	stdtime "time"
 
@@ -27,11 +29,13 @@ import (
 
 	"helm.sh/helm/v3/pkg/chart"
 	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/storage/driver"
 	"helm.sh/helm/v3/pkg/time"
 
 	v2chrtutil "k8s.io/helm/pkg/chartutil"
 	v2chart "k8s.io/helm/pkg/proto/hapi/chart"
 	v2rls "k8s.io/helm/pkg/proto/hapi/release"
+	sigsyaml "sigs.k8s.io/yaml"
 
 	common "github.com/helm/helm-2to3/pkg/common"
 )
@@ -66,10 +70,12 @@ func CreateRelease(v2Rel *v2rls.Release) (*release.Release, error) {
 		return nil, err
 	}
 
-	hooks, err := mapHooks(v2Rel.Hooks, v2Rel.Info.Status.LastTestSuiteRun)
+	hooks, crds, err := mapHooks(v2Rel.Hooks, v2Rel.Info.Status.LastTestSuiteRun)
 	if err != nil {
 		return nil, err
 	}
+	// Helm v3 installs CRDs from the 'crds' directory of the chart instead of crd-install hooks
+	chrt.Files = append(chrt.Files, crds...)
 
 	return &release.Release{
 		Name:      v2Rel.Name,
@@ -100,6 +106,32 @@ func StoreRelease(rel *release.Release, kubeConfig common.KubeConfig) error {
 	return cfg.Releases.Create(rel)
 }
 
+// GetReleaseHistory returns all release versions from Helm v3 storage for a specified release.
+// It returns an empty history if the release does not exist.
+func GetReleaseHistory(name, namespace string, kubeConfig common.KubeConfig) ([]*release.Release, error) {
+	cfg, err := GetActionConfig(namespace, kubeConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := cfg.Releases.History(name)
+	if errors.Is(err, driver.ErrReleaseNotFound) {
+		return []*release.Release{}, nil
+	}
+	return history, err
+}
+
+// DeleteRelease deletes a release object from Helm v3 storage
+func DeleteRelease(rel *release.Release, kubeConfig common.KubeConfig) error {
+	cfg, err := GetActionConfig(rel.Namespace, kubeConfig)
+	if err != nil {
+		return err
+	}
+
+	_, err = cfg.Releases.Delete(rel.Name, rel.Version)
+	return err
+}
+
 func mapv2ChartTov3Chart(v2Chrt *v2chart.Chart) (*chart.Chart, error) {
 	v3Chrt := new(chart.Chart)
 	v3Chrt.Metadata = mapMetadata(v2Chrt)
@@ -114,12 +146,34 @@ func mapv2ChartTov3Chart(v2Chrt *v2chart.Chart) (*chart.Chart, error) {
 	v3Chrt.Files = mapFiles(v2Chrt.Files)
 	// Schema is set to nil as Schema wass introduced in Helm v3
 	v3Chrt.Schema = nil
-	// Lock is set to nil because v2 does not save the requirements lock file details
-	// v3 should gather data as need be from the chart dependencies
-	v3Chrt.Lock = nil
+	if err := mapRequirements(v3Chrt); err != nil {
+		return nil, err
+	}
 	return v3Chrt, nil
 }
 
+// mapRequirements sets the chart dependencies and lock from the requirements.yaml and
+// requirements.lock files of a v2 chart, as Helm v3 does when loading an apiVersion v1 chart
+func mapRequirements(chrt *chart.Chart) error {
+	for _, f := range chrt.Files {
+		switch f.Name {
+		case "requirements.yaml":
+			if chrt.Metadata == nil {
+				chrt.Metadata = new(chart.Metadata)
+			}
+			if err := sigsyaml.Unmarshal(f.Data, chrt.Metadata); err != nil {
+				return fmt.Errorf("Failed to parse requirements.yaml of chart \"%s\" due to the following error: %s", chrt.Name(), err)
+			}
+		case "requirements.lock":
+			chrt.Lock = new(chart.Lock)
+			if err := sigsyaml.Unmarshal(f.Data, chrt.Lock); err != nil {
+				return fmt.Errorf("Failed to parse requirements.lock of chart \"%s\" due to the following error: %s", chrt.Name(), err)
+			}
+		}
+	}
+	return nil
+}
+
 func mapMetadata(v2Chrt *v2chart.Chart) *chart.Metadata {
 	if v2Chrt.Metadata == nil {
 		return nil
@@ -140,8 +194,7 @@ func mapMetadata(v2Chrt *v2chart.Chart) *chart.Metadata {
 	metadata.Deprecated = v2Chrt.Metadata.Deprecated
 	metadata.Annotations = v2Chrt.Metadata.Annotations
 	metadata.KubeVersion = v2Chrt.Metadata.KubeVersion
-	// v2 does not save the dependency metadata from requirements, so setting to nil
-	// v3 should gather data as need be from the chart dependencies
+	// The dependencies are set from the requirements.yaml file of the chart
 	metadata.Dependencies = nil
 	//Default to application
 	metadata.Type = "application"
@@ -233,53 +286,83 @@ func mapStatus(v2Info *v2rls.Info) (string, error) {
 	return v3StatusStr, nil
 }
 
-func mapHooks(v2Hooks []*v2rls.Hook, v2LastTestSuiteRun *v2rls.TestSuite) ([]*release.Hook, error) {
+func mapHooks(v2Hooks []*v2rls.Hook, v2LastTestSuiteRun *v2rls.TestSuite) ([]*release.Hook, []*chart.File, error) {
 	if v2Hooks == nil {
-		return nil, nil
+		return nil, nil, nil
 	}
 	hooks := []*release.Hook{}
+	crds := []*chart.File{}
+	crdsByName := map[string]*chart.File{}
 	for _, val := range v2Hooks {
 		hook := new(release.Hook)
 		hook.Name = val.Name
 		hook.Kind = val.Kind
 		hook.Path = val.Path
 		hook.Manifest = val.Manifest
-		events, err := mapHookEvents(val.Events)
+		events, isCRDInstall, err := mapHookEvents(val.Events)
 		if err != nil {
-			return nil, err
+			return nil, nil, fmt.Errorf("Failed to map hook \"%s\" due to the following error: %s", val.Name, err)
+		}
+		if isCRDInstall {
+			// Manifests of the hooks in the same template are kept together in one CRD file, named
+			// after the full template path, as templates of subcharts can have the same name
+			name := path.Join("crds", val.Path)
+			if !strings.HasPrefix(name, "crds/") {
+				return nil, nil, fmt.Errorf("Failed to map hook \"%s\" as its template path \"%s\" is not in the chart", val.Name, val.Path)
+			}
+			crd, ok := crdsByName[name]
+			if !ok {
+				crd = &chart.File{Name: name}
+				crdsByName[name] = crd
+				crds = append(crds, crd)
+			} else {
+				crd.Data = append(crd.Data, []byte("\n---\n")...)
+			}
+			crd.Data = append(crd.Data, []byte(strings.TrimSpace(val.Manifest)+"\n")...)
+			// A crd-install hook with no other event is dropped
+			if len(events) == 0 {
+				continue
+			}
 		}
 		hook.Events = events
 		hook.Weight = int(val.Weight)
-		if err != nil {
-			return nil, err
-		}
 		policies, err := mapHookDeletePolicies(val.DeletePolicies)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		hook.DeletePolicies = policies
 		var lastRun *release.HookExecution
 		lastRun, err = mapTestSuiteToHookExecution(hook.Name, v2LastTestSuiteRun)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		if lastRun != nil {
 			hook.LastRun = *lastRun
 		}
 		hooks = append(hooks, hook)
 	}
-	return hooks, nil
+	return hooks, crds, nil
 }
 
-func mapHookEvents(v2HookEvents []v2rls.Hook_Event) ([]release.HookEvent, error) {
+// mapHookEvents maps v2 hook events to v3 hook events. The crd-install event has no v3 hook event
+// equivalent, so it is not mapped and is reported separately.
+func mapHookEvents(v2HookEvents []v2rls.Hook_Event) ([]release.HookEvent, bool, error) {
 	if v2HookEvents == nil {
-		return nil, nil
+		return nil, false, nil
 	}
 	hookEvents := []release.HookEvent{}
+	isCRDInstall := false
 	for _, val := range v2HookEvents {
 		v2EventStr, ok := v2rls.Hook_Event_name[int32(val)]
 		if !ok {
-			return nil, fmt.Errorf("Failed to get the v2 hook event string")
+			return nil, false, fmt.Errorf("Failed to get the v2 hook event string")
+		}
+		switch val {
+		case v2rls.Hook_CRD_INSTALL:
+			isCRDInstall = true
+			continue
+		case v2rls.Hook_UNKNOWN:
+			return nil, false, fmt.Errorf("v2 hook event \"%s\" has no Helm v3 equivalent", v2EventStr)
 		}
 
 		// map to v3 hook event
@@ -292,7 +375,7 @@ func mapHookEvents(v2HookEvents []v2rls.Hook_Event) ([]release.HookEvent, error)
 		event := release.HookEvent(v3EventStr)
 		hookEvents = append(hookEvents, event)
 	}
-	return hookEvents, nil
+	return hookEvents, isCRDInstall, nil
 }
 
 func mapHookDeletePolicies(v2HookDelPolicies []v2rls.Hook_DeletePolicy) ([]release.HookDeletePolicy, error) {
@@ -0,0 +1,162 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/golang/protobuf/ptypes/any"
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+	v2chart "k8s.io/helm/pkg/proto/hapi/chart"
+	v2rls "k8s.io/helm/pkg/proto/hapi/release"
+)
+
+// newV2Release returns a Helm v2 release version of the chart 'app' with hooks
+func newV2Release(hooks ...*v2rls.Hook) *v2rls.Release {
+	return &v2rls.Release{
+		Name:      "app",
+		Namespace: "default",
+		Version:   1,
+		Chart: &v2chart.Chart{
+			Metadata: &v2chart.Metadata{Name: "app", Version: "1.0.0", ApiVersion: "v1"},
+		},
+		Info: &v2rls.Info{
+			Status: &v2rls.Status{Code: v2rls.Status_DEPLOYED},
+		},
+		Hooks: hooks,
+	}
+}
+
+func TestMapHooksCRDs(t *testing.T) {
+	crdInstall := []v2rls.Hook_Event{v2rls.Hook_CRD_INSTALL}
+	v2Release := newV2Release(
+		&v2rls.Hook{Name: "a", Kind: "CustomResourceDefinition", Path: "app/templates/crd.yaml", Manifest: "kind: CustomResourceDefinition\nmetadata:\n  name: a\n", Events: crdInstall},
+		&v2rls.Hook{Name: "b", Kind: "CustomResourceDefinition", Path: "app/templates/crd.yaml", Manifest: "kind: CustomResourceDefinition\nmetadata:\n  name: b\n", Events: crdInstall},
+		&v2rls.Hook{Name: "c", Kind: "CustomResourceDefinition", Path: "app/charts/sub/templates/crd.yaml", Manifest: "kind: CustomResourceDefinition\nmetadata:\n  name: c\n", Events: crdInstall},
+		&v2rls.Hook{Name: "d", Kind: "CustomResourceDefinition", Path: "app/templates/other.yaml", Manifest: "kind: CustomResourceDefinition\nmetadata:\n  name: d\n", Events: []v2rls.Hook_Event{v2rls.Hook_CRD_INSTALL, v2rls.Hook_POST_INSTALL}},
+		&v2rls.Hook{Name: "e", Kind: "Job", Path: "app/templates/job.yaml", Manifest: "kind: Job\nmetadata:\n  name: e\n", Events: []v2rls.Hook_Event{v2rls.Hook_PRE_UPGRADE}},
+	)
+
+	rel, err := CreateRelease(v2Release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The CRD file names are prefixed with the chart path by Helm v3
+	crds := map[string]string{}
+	for _, crd := range rel.Chart.CRDObjects() {
+		crds[crd.Filename] = string(crd.File.Data)
+	}
+	wantCRDs := map[string]string{
+		"app/crds/app/templates/crd.yaml":            "kind: CustomResourceDefinition\nmetadata:\n  name: a\n\n---\nkind: CustomResourceDefinition\nmetadata:\n  name: b\n",
+		"app/crds/app/charts/sub/templates/crd.yaml": "kind: CustomResourceDefinition\nmetadata:\n  name: c\n",
+		"app/crds/app/templates/other.yaml":          "kind: CustomResourceDefinition\nmetadata:\n  name: d\n",
+	}
+	if !reflect.DeepEqual(crds, wantCRDs) {
+		t.Errorf("CRD files are %q, want %q", crds, wantCRDs)
+	}
+
+	hooks := map[string][]release.HookEvent{}
+	for _, hook := range rel.Hooks {
+		hooks[hook.Name] = hook.Events
+	}
+	wantHooks := map[string][]release.HookEvent{
+		"d": {release.HookPostInstall},
+		"e": {release.HookPreUpgrade},
+	}
+	if !reflect.DeepEqual(hooks, wantHooks) {
+		t.Errorf("hooks are %v, want %v", hooks, wantHooks)
+	}
+}
+
+func TestMapHooksCRDOutsideChart(t *testing.T) {
+	v2Release := newV2Release(&v2rls.Hook{Name: "a", Path: "../crd.yaml", Events: []v2rls.Hook_Event{v2rls.Hook_CRD_INSTALL}})
+	if _, err := CreateRelease(v2Release); err == nil {
+		t.Error("CreateRelease() succeeded, want error for a hook template outside the chart")
+	}
+}
+
+func TestMapRequirements(t *testing.T) {
+	requirements := `dependencies:
+- name: db
+  version: 1.2.3
+  repository: https://charts.example.com
+  condition: db.enabled
+  tags:
+  - backend
+  alias: database
+  import-values:
+  - child: exports.data
+    parent: dbData
+  - defaults
+`
+	lock := `dependencies:
+- name: db
+  version: 1.2.3
+  repository: https://charts.example.com
+digest: sha256:0123
+generated: "2020-01-01T00:00:00Z"
+`
+	v2Release := newV2Release()
+	v2Release.Chart.Files = []*any.Any{
+		{TypeUrl: "requirements.yaml", Value: []byte(requirements)},
+		{TypeUrl: "requirements.lock", Value: []byte(lock)},
+	}
+	v2Release.Chart.Dependencies = []*v2chart.Chart{
+		{Metadata: &v2chart.Metadata{Name: "db", Version: "1.2.3", ApiVersion: "v1"}},
+	}
+
+	rel, err := CreateRelease(v2Release)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantDependencies := []*chart.Dependency{{
+		Name:       "db",
+		Version:    "1.2.3",
+		Repository: "https://charts.example.com",
+		Condition:  "db.enabled",
+		Tags:       []string{"backend"},
+		Alias:      "database",
+		ImportValues: []interface{}{
+			map[string]interface{}{"child": "exports.data", "parent": "dbData"},
+			"defaults",
+		},
+	}}
+	if !reflect.DeepEqual(rel.Chart.Metadata.Dependencies, wantDependencies) {
+		t.Errorf("dependencies are %+v, want %+v", rel.Chart.Metadata.Dependencies[0], wantDependencies[0])
+	}
+	if rel.Chart.Metadata.Name != "app" || rel.Chart.Metadata.APIVersion != "v1" {
+		t.Errorf("chart metadata was overwritten by requirements.yaml: %+v", rel.Chart.Metadata)
+	}
+	if rel.Chart.Lock == nil || rel.Chart.Lock.Digest != "sha256:0123" || len(rel.Chart.Lock.Dependencies) != 1 || rel.Chart.Lock.Dependencies[0].Name != "db" {
+		t.Errorf("lock is %+v, want the one of requirements.lock", rel.Chart.Lock)
+	}
+	if len(rel.Chart.Dependencies()) != 1 || rel.Chart.Dependencies()[0].Name() != "db" {
+		t.Errorf("dependency charts are not mapped")
+	}
+}
+
+func TestMapRequirementsInvalid(t *testing.T) {
+	v2Release := newV2Release()
+	v2Release.Chart.Files = []*any.Any{{TypeUrl: "requirements.yaml", Value: []byte("dependencies: {")}}
+	if _, err := CreateRelease(v2Release); err == nil {
+		t.Error("CreateRelease() succeeded, want error for the invalid requirements.yaml")
+	}
+}
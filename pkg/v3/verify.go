@@ -0,0 +1,195 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"helm.sh/helm/v3/pkg/release"
+	"helm.sh/helm/v3/pkg/time"
+)
+
+// Difference is a field which differs between an expected and an actual release version
+type Difference struct {
+	Field string
+	// Lines is a line diff of the field, lines prefixed with '-' are expected and '+' are actual
+	Lines []string
+}
+
+// CompareReleases compares the fields of an actual release version with the expected one.
+// The expected release version is normally mapped from Helm v2 using CreateRelease.
+func CompareReleases(expected, actual *release.Release) ([]Difference, error) {
+	diffs := []Difference{}
+	add := func(field, expectedValue, actualValue string) {
+		if expectedValue != actualValue {
+			diffs = append(diffs, Difference{Field: field, Lines: DiffLines(expectedValue, actualValue)})
+		}
+	}
+	addJSON := func(field string, expectedValue, actualValue interface{}) error {
+		expectedStr, err := toJSON(expectedValue)
+		if err != nil {
+			return err
+		}
+		actualStr, err := toJSON(actualValue)
+		if err != nil {
+			return err
+		}
+		add(field, expectedStr, actualStr)
+		return nil
+	}
+
+	add("name", expected.Name, actual.Name)
+	add("namespace", expected.Namespace, actual.Namespace)
+	add("version", fmt.Sprint(expected.Version), fmt.Sprint(actual.Version))
+	add("manifest", expected.Manifest, actual.Manifest)
+	if err := addJSON("values", expected.Config, actual.Config); err != nil {
+		return nil, err
+	}
+	if err := addJSON("hooks", expected.Hooks, actual.Hooks); err != nil {
+		return nil, err
+	}
+
+	var expectedMetadata, actualMetadata interface{}
+	if expected.Chart != nil {
+		expectedMetadata = expected.Chart.Metadata
+	}
+	if actual.Chart != nil {
+		actualMetadata = actual.Chart.Metadata
+	}
+	if err := addJSON("chart.metadata", expectedMetadata, actualMetadata); err != nil {
+		return nil, err
+	}
+
+	expectedInfo := expected.Info
+	if expectedInfo == nil {
+		expectedInfo = &release.Info{}
+	}
+	actualInfo := actual.Info
+	if actualInfo == nil {
+		actualInfo = &release.Info{}
+	}
+	add("info.status", expectedInfo.Status.String(), actualInfo.Status.String())
+	add("info.description", expectedInfo.Description, actualInfo.Description)
+	add("info.notes", expectedInfo.Notes, actualInfo.Notes)
+	add("info.firstDeployed", formatTime(expectedInfo.FirstDeployed), formatTime(actualInfo.FirstDeployed))
+	add("info.lastDeployed", formatTime(expectedInfo.LastDeployed), formatTime(actualInfo.LastDeployed))
+	add("info.deleted", formatTime(expectedInfo.Deleted), formatTime(actualInfo.Deleted))
+
+	return diffs, nil
+}
+
+// DiffLines returns the lines which differ between two strings. Lines only in the expected
+// string are prefixed with '-' and lines only in the actual string with '+'.
+func DiffLines(expected, actual string) []string {
+	lines := []string{}
+	diffLines(strings.Split(expected, "\n"), strings.Split(actual, "\n"), &lines)
+	return lines
+}
+
+// diffLines appends the lines which differ between a and b. It splits a in two halves and b where
+// the longest common subsequence of lines crosses the split (Hirschberg), so that the memory used
+// is linear in the number of lines, even for large manifests.
+func diffLines(a, b []string, lines *[]string) {
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		a, b = a[1:], b[1:]
+	}
+	for len(a) > 0 && len(b) > 0 && a[len(a)-1] == b[len(b)-1] {
+		a, b = a[:len(a)-1], b[:len(b)-1]
+	}
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			*lines = append(*lines, "+"+line)
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			*lines = append(*lines, "-"+line)
+		}
+	case len(a) == 1:
+		for j, line := range b {
+			if line == a[0] {
+				diffLines(nil, b[:j], lines)
+				diffLines(nil, b[j+1:], lines)
+				return
+			}
+		}
+		*lines = append(*lines, "-"+a[0])
+		diffLines(nil, b, lines)
+	default:
+		mid := len(a) / 2
+		forward := lcsLengths(a[:mid], b, false)
+		backward := lcsLengths(a[mid:], b, true)
+		split, longest := 0, -1
+		for k := 0; k <= len(b); k++ {
+			if length := forward[k] + backward[len(b)-k]; length > longest {
+				split, longest = k, length
+			}
+		}
+		diffLines(a[:mid], b[:split], lines)
+		diffLines(a[mid:], b[split:], lines)
+	}
+}
+
+// lcsLengths returns the lengths of the longest common subsequences of a and the first j lines
+// of b, for each j. When reverse is set, a and b are read from the end, so that the lengths are for
+// the last j lines of b.
+func lcsLengths(a, b []string, reverse bool) []int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for i := range a {
+		lineA := a[i]
+		if reverse {
+			lineA = a[len(a)-1-i]
+		}
+		for j := 1; j <= len(b); j++ {
+			lineB := b[j-1]
+			if reverse {
+				lineB = b[len(b)-j]
+			}
+			switch {
+			case lineA == lineB:
+				current[j] = previous[j-1] + 1
+			case previous[j] >= current[j-1]:
+				current[j] = previous[j]
+			default:
+				current[j] = current[j-1]
+			}
+		}
+		previous, current = current, previous
+	}
+	return previous
+}
+
+func toJSON(value interface{}) (string, error) {
+	// Values are marshalled as JSON so that maps compare independently of the
+	// number types produced by the YAML and JSON decoders
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format("2006-01-02T15:04:05.999999999Z07:00")
+}
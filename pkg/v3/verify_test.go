@@ -0,0 +1,151 @@
+/*
+Copyright The Helm Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v3
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"helm.sh/helm/v3/pkg/chart"
+	"helm.sh/helm/v3/pkg/release"
+)
+
+func newRelease() *release.Release {
+	return &release.Release{
+		Name:      "app",
+		Namespace: "default",
+		Version:   1,
+		Manifest:  "---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n",
+		Config:    map[string]interface{}{"replicas": 1},
+		Hooks: []*release.Hook{
+			{Name: "app-test", Kind: "Pod", Path: "app/templates/test.yaml", Events: []release.HookEvent{release.HookTest}},
+		},
+		Chart: &chart.Chart{Metadata: &chart.Metadata{Name: "app", Version: "1.0.0"}},
+		Info:  &release.Info{Status: release.StatusDeployed, Description: "Install complete"},
+	}
+}
+
+func TestCompareReleases(t *testing.T) {
+	tests := []struct {
+		name       string
+		change     func(rel *release.Release)
+		wantFields []string
+	}{
+		{name: "match", change: func(rel *release.Release) {}, wantFields: []string{}},
+		{name: "manifest", change: func(rel *release.Release) { rel.Manifest = strings.Replace(rel.Manifest, "name: app", "name: web", 1) }, wantFields: []string{"manifest"}},
+		{name: "values", change: func(rel *release.Release) { rel.Config["replicas"] = 2 }, wantFields: []string{"values"}},
+		{name: "hooks", change: func(rel *release.Release) { rel.Hooks[0].Events = []release.HookEvent{release.HookPreInstall} }, wantFields: []string{"hooks"}},
+		{name: "status", change: func(rel *release.Release) { rel.Info.Status = release.StatusSuperseded }, wantFields: []string{"info.status"}},
+		{name: "missing info", change: func(rel *release.Release) { rel.Info = nil }, wantFields: []string{"info.status", "info.description"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := newRelease()
+			tt.change(actual)
+			diffs, err := CompareReleases(newRelease(), actual)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fields := []string{}
+			for _, diff := range diffs {
+				fields = append(fields, diff.Field)
+				if len(diff.Lines) == 0 {
+					t.Errorf("difference of %s has no lines", diff.Field)
+				}
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Errorf("CompareReleases() differs in %v, want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		actual   string
+		want     []string
+	}{
+		{name: "match", expected: "a\nb", actual: "a\nb", want: []string{}},
+		{name: "changed line", expected: "a\nb\nc", actual: "a\nx\nc", want: []string{"-b", "+x"}},
+		{name: "added line", expected: "a\nc", actual: "a\nb\nc", want: []string{"+b"}},
+		{name: "removed lines", expected: "a\nb\nc\nd", actual: "a\nd", want: []string{"-b", "-c"}},
+		{name: "empty", expected: "", actual: "a", want: []string{"-", "+a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffLines(tt.expected, tt.actual); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffLines() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestDiffLinesMinimal checks that the diff is as short as the one of the longest common
+// subsequence, and that it turns the expected lines into the actual lines
+func TestDiffLinesMinimal(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, random.Intn(12))
+		for i := range lines {
+			lines[i] = fmt.Sprint(random.Intn(4))
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		expected, actual := strings.Join(randomLines(), "\n"), strings.Join(randomLines(), "\n")
+		a, b := strings.Split(expected, "\n"), strings.Split(actual, "\n")
+		lines := DiffLines(expected, actual)
+		if want := len(a) + len(b) - 2*lcsLength(a, b); len(lines) != want {
+			t.Fatalf("DiffLines(%q, %q) has %d lines, want %d: %q", expected, actual, len(lines), want, lines)
+		}
+		removed := 0
+		for _, line := range lines {
+			if strings.HasPrefix(line, "-") {
+				removed++
+			}
+		}
+		if len(a)-removed != len(b)-(len(lines)-removed) {
+			t.Fatalf("DiffLines(%q, %q) = %q does not keep the same lines on both sides", expected, actual, lines)
+		}
+	}
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b with the full table
+func lcsLength(a, b []string) int {
+	table := make([][]int, len(a)+1)
+	for i := range table {
+		table[i] = make([]int, len(b)+1)
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			switch {
+			case a[i-1] == b[j-1]:
+				table[i][j] = table[i-1][j-1] + 1
+			case table[i-1][j] >= table[i][j-1]:
+				table[i][j] = table[i-1][j]
+			default:
+				table[i][j] = table[i][j-1]
+			}
+		}
+	}
+	return table[len(a)][len(b)]
+}